@@ -0,0 +1,258 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+// Command confcheck checks the config files of the config package.
+//
+// Usage:
+//
+//	confcheck lint file...
+//	confcheck print [-profile name] file...
+//	confcheck diff [-json] old new
+//
+// lint reports the parse errors, the duplicate keys and the suspicious
+// whitespace. print shows the effective options of the files, a later file
+// overrides an earlier one, with the interpolated values and the layers which
+// supply them, the -profile flag applies to the files in the config file
+// format. diff shows the added, removed and changed keys.
+//
+// The exit status is 0 if all is fine, 1 if lint finds problems, print finds
+// malformed values or diff finds changes, and 2 on the usage or load errors.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"github.com/roverli/utils/config"
+	"github.com/roverli/utils/errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const usage = `usage:
+	confcheck lint file...
+	confcheck print [-profile name] file...
+	confcheck diff [-json] old new
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// Run the command with args, return the exit status.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	switch args[0] {
+	case "lint":
+		return lintCmd(args[1:], stdout, stderr)
+	case "print":
+		return printCmd(args[1:], stdout, stderr)
+	case "diff":
+		return diffCmd(args[1:], stdout, stderr)
+	}
+	fmt.Fprintf(stderr, "confcheck: unknown command %s\n%s", args[0], usage)
+	return 2
+}
+
+func lintCmd(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil || fs.NArg() == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	status := 0
+	for _, fname := range fs.Args() {
+		problems, err := lintFile(fname)
+		if err != nil {
+			fmt.Fprintf(stderr, "confcheck: %v\n", err)
+			return 2
+		}
+		for _, p := range problems {
+			fmt.Fprintln(stdout, p)
+			status = 1
+		}
+	}
+	return status
+}
+
+// Return the problems of the file, the error is returned if the file cann't be read.
+func lintFile(fname string) ([]string, error) {
+	content, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := []string{}
+	if _, err := config.LoadAny(fname); err != nil {
+		problems = append(problems, fname+": "+err.Message())
+	}
+	switch strings.ToLower(filepath.Ext(fname)) {
+	case ".json", ".toml", ".properties", ".env":
+		return problems, nil
+	}
+	return append(problems, lintLines(fname, string(content))...), nil
+}
+
+// The whitespace chars which look like a space but are not trimmed.
+var oddSpaces = []rune{'\u00a0', '\u2007', '\u200b', '\u202f', '\u3000', '\ufeff'}
+
+// Return the duplicate keys and suspicious whitespace of the config file content.
+func lintLines(fname string, content string) []string {
+	problems := []string{}
+	report := func(n int, format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf("%s:%d: ", fname, n)+fmt.Sprintf(format, args...))
+	}
+
+	// The line of the first definition by profile and key.
+	seen := make(map[string]int)
+	prefix, profile := "", ""
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for n := 1; scanner.Scan(); n++ {
+		raw := strings.TrimRight(scanner.Text(), "\r")
+		for _, r := range oddSpaces {
+			if strings.ContainsRune(raw, r) {
+				report(n, "invisible whitespace U+%04X", r)
+			}
+		}
+		if strings.TrimRight(raw, " \t") != raw {
+			report(n, "trailing whitespace")
+		}
+
+		text := strings.TrimSpace(raw)
+		switch {
+		case text == "" || text[0] == '#' || strings.HasPrefix(text, "include ") || strings.HasPrefix(text, "include\t"):
+		case text[0] == '[':
+			name := strings.TrimSpace(strings.Trim(text, "[]"))
+			prefix, profile = name+".", ""
+			if strings.HasPrefix(name, "profile:") {
+				prefix, profile = "", strings.TrimSpace(name[len("profile:"):])
+			}
+		default:
+			i := strings.Index(text, "=")
+			if i < 0 {
+				continue
+			}
+			key, keyProfile := strings.TrimSpace(text[:i]), profile
+			if j := strings.LastIndex(key, "@"); j >= 0 {
+				key, keyProfile = strings.TrimSpace(key[:j]), strings.TrimSpace(key[j+1:])
+			}
+			if strings.ContainsAny(key, " \t") {
+				report(n, "whitespace in key %q", key)
+			}
+
+			// The repeated "list[] = value" lines are the elements of a list.
+			if strings.HasSuffix(key, "[]") {
+				continue
+			}
+			id := keyProfile + "@" + prefix + key
+			if first, ok := seen[id]; ok {
+				report(n, "duplicate key %s, first defined at line %d", prefix+key, first)
+			} else {
+				seen[id] = n
+			}
+		}
+	}
+	return problems
+}
+
+func printCmd(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("print", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	profile := fs.String("profile", "", "the comma separated profiles to activate")
+	if err := fs.Parse(args); err != nil || fs.NArg() == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	// The later files override the earlier ones.
+	sources := []config.Source{}
+	for _, fname := range fs.Args() {
+		conf, err := load(fname, *profile)
+		if err != nil {
+			fmt.Fprintf(stderr, "confcheck: %s\n", err.Message())
+			return 2
+		}
+		sources = append([]config.Source{config.ConfigSource("file "+fname, conf)}, sources...)
+	}
+	conf := config.Layered(sources...)
+
+	keys := conf.Keys()
+	sort.Strings(keys)
+	status := 0
+	for _, k := range keys {
+		v, err := conf.StringE(k, "")
+		switch {
+		case conf.IsSecret(k):
+			v = "******"
+		case err != nil:
+			fmt.Fprintf(stderr, "confcheck: %s\n", err.Message())
+			status = 1
+			continue
+		}
+		fmt.Fprintf(stdout, "%s = %s\n", k, v)
+
+		// The first line of Explain is the raw value, the others are the layers.
+		layers := strings.Split(conf.Explain(k), "\n")
+		for _, l := range layers[1:] {
+			fmt.Fprintln(stdout, l)
+		}
+	}
+	return status
+}
+
+// Load the file in the format of its extension, with the profiles for the
+// config file format. The other formats have no profiles.
+func load(fname string, profile string) (*config.Config, errors.Error) {
+	switch strings.ToLower(filepath.Ext(fname)) {
+	case ".json", ".toml", ".properties", ".env":
+		profile = ""
+	}
+	if profile == "" {
+		return config.LoadAny(fname)
+	}
+	return config.LoadProfile(fname, strings.Split(profile, ",")...)
+}
+
+func diffCmd(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	asJSON := fs.Bool("json", false, "write the changes as JSON")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 2 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	confs := make([]*config.Config, 2)
+	for i, fname := range fs.Args() {
+		conf, err := config.LoadAny(fname)
+		if err != nil {
+			fmt.Fprintf(stderr, "confcheck: %s\n", err.Message())
+			return 2
+		}
+		confs[i] = conf
+	}
+
+	changes := config.Diff(confs[0], confs[1])
+	if *asJSON {
+		if err := changes.WriteJSON(stdout); err != nil {
+			fmt.Fprintf(stderr, "confcheck: %s\n", err.Message())
+			return 2
+		}
+	} else {
+		fmt.Fprint(stdout, changes)
+	}
+	if changes.IsEmpty() {
+		return 0
+	}
+	return 1
+}
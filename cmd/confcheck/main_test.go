@@ -0,0 +1,107 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func runArgs(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	status := run(args, &stdout, &stderr)
+	return status, stdout.String(), stderr.String()
+}
+
+func TestLint(t *testing.T) {
+	status, out, _ := runArgs("lint", "testdata/lint.conf")
+	if status != 1 {
+		t.Errorf("Expected status 1, but was %d", status)
+	}
+	expected := "testdata/lint.conf:4: trailing whitespace\n" +
+		"testdata/lint.conf:6: duplicate key db.host, first defined at line 4\n" +
+		"testdata/lint.conf:7: whitespace in key \"max conns\"\n" +
+		"testdata/lint.conf:9: invisible whitespace U+00A0\n"
+	if out != expected {
+		t.Errorf("Expected lint output:\n%s\nbut was:\n%s", expected, out)
+	}
+
+	status, out, _ = runArgs("lint", "testdata/bad.conf")
+	if status != 1 || !strings.Contains(out, "parse error: bad line (testdata/bad.conf:2)") {
+		t.Errorf("Expected parse error at line 2, but was %d %s", status, out)
+	}
+
+	if status, out, _ = runArgs("lint", "testdata/base.conf", "testdata/prod.conf"); status != 0 || out != "" {
+		t.Errorf("Expected no problems, but was %d %s", status, out)
+	}
+	if status, _, _ = runArgs("lint", "testdata/missing.conf"); status != 2 {
+		t.Errorf("Expected status 2 for missing file, but was %d", status)
+	}
+}
+
+func TestPrint(t *testing.T) {
+	status, out, _ := runArgs("print", "testdata/base.conf", "testdata/prod.conf")
+	if status != 0 {
+		t.Errorf("Expected status 0, but was %d", status)
+	}
+	expected := `db.host = db.prod
+  * file testdata/prod.conf: db.prod
+    file testdata/base.conf: localhost
+db.port = 3306
+  * file testdata/base.conf: 3306
+db.url = mysql://db.prod:3306
+  * file testdata/base.conf: mysql://${db.host}:${db.port}
+db.user = root
+  * file testdata/prod.conf: root
+name = app
+  * file testdata/base.conf: app
+`
+	if out != expected {
+		t.Errorf("Expected print output:\n%s\nbut was:\n%s", expected, out)
+	}
+}
+
+func TestPrintProfileJSON(t *testing.T) {
+	status, out, errOut := runArgs("print", "-profile", "prod", "testdata/base.conf", "testdata/app.json")
+	if status != 0 {
+		t.Errorf("Expected status 0, but was %d: %s", status, errOut)
+	}
+	if !strings.Contains(out, "db.host = db.json\n") || !strings.Contains(out, "name = app\n") {
+		t.Errorf("Expected options of both files, but was:\n%s", out)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	status, out, _ := runArgs("diff", "testdata/base.conf", "testdata/prod.conf")
+	if status != 1 {
+		t.Errorf("Expected status 1, but was %d", status)
+	}
+	expected := "~ db.host: localhost -> db.prod\n" +
+		"- db.port = 3306\n" +
+		"- db.url = mysql://${db.host}:${db.port}\n" +
+		"+ db.user = root\n" +
+		"- name = app\n"
+	if out != expected {
+		t.Errorf("Expected diff output:\n%s\nbut was:\n%s", expected, out)
+	}
+
+	status, out, _ = runArgs("diff", "-json", "testdata/base.conf", "testdata/base.conf")
+	var changes map[string][]interface{}
+	if status != 0 || json.Unmarshal([]byte(out), &changes) != nil || len(changes["changed"]) != 0 {
+		t.Errorf("Expected no changes in json, but was %d %s", status, out)
+	}
+
+	if status, _, _ = runArgs("diff", "testdata/base.conf"); status != 2 {
+		t.Errorf("Expected usage error, but was %d", status)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	if status, _, errOut := runArgs("check"); status != 2 || !strings.Contains(errOut, "unknown command") {
+		t.Errorf("Expected unknown command error, but was %d %s", status, errOut)
+	}
+}
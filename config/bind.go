@@ -0,0 +1,317 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"github.com/roverli/utils/errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The struct tag name used by Unmarshal.
+const tagName = "conf"
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// Unmarshal fills the struct pointed by v with the config options.
+//
+// The struct fields are bound by the "conf" tag, the tag value is the key name
+// followed by optional comma separated flags:
+//
+//	type DB struct {
+//		Host    string        `conf:"host,default=localhost"`
+//		Port    int           `conf:"port,required"`
+//		Timeout time.Duration `conf:"timeout,default=3s"`
+//		Slaves  []string      `conf:"slaves"`
+//	}
+//
+//	type Settings struct {
+//		DB DB `conf:"db"` // keys are "db.host", "db.port" ...
+//	}
+//
+// A tagged struct field is a key group, its name is the prefix of the inner keys.
+// An untagged struct field is flattened into its parent. Other untagged fields
+// and the fields tagged with "-" are ignored. A pointer group of a struct type
+// being bound, e.g. "Next *Node" in Node, is bound only if a key has its prefix.
+//
+// Supported field kinds are string, bool, int*, uint*, float*, time.Duration,
+// time.Time in RFC3339, structs, pointers to them and slices of the scalar
+// kinds. The values are parsed as the typed getters, e.g. slices are read as
+// List: the "key[] = value" lines and the arrays are the elements, a plain
+// value is split as StringSlice.
+//
+// All the missing required keys and malformed values are reported in one error.
+func Unmarshal(c *Config, v interface{}) errors.Error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.Newf("config: unmarshal target must be a non-nil struct pointer, but was %T", v)
+	}
+
+	b := &binder{c: c, s: c.load(), visiting: make(map[reflect.Type]bool)}
+	b.keys = b.s.keys()
+	b.bindStruct("", rv.Elem())
+	if len(b.problems) > 0 {
+		return errors.Newf("config: unmarshal %s failed:\n\t%s",
+			rv.Elem().Type(), strings.Join(b.problems, "\n\t"))
+	}
+	return nil
+}
+
+// Bind is the method form of Unmarshal.
+func (c *Config) Bind(v interface{}) errors.Error {
+	return Unmarshal(c, v)
+}
+
+// Inner struct tag representation.
+type fieldTag struct {
+	name       string
+	defaultv   string
+	hasDefault bool
+	required   bool
+}
+
+// Inner method, parse the "conf" struct tag.
+// The default value must be the last flag if it contains commas.
+func parseTag(tag string) fieldTag {
+	parts := strings.Split(tag, ",")
+	ft := fieldTag{name: strings.TrimSpace(parts[0])}
+	for i := 1; i < len(parts); i++ {
+		part := strings.TrimSpace(parts[i])
+		switch {
+		case part == "required":
+			ft.required = true
+		case strings.HasPrefix(part, "default="):
+			ft.defaultv = strings.TrimPrefix(part, "default=")
+			if i+1 < len(parts) {
+				ft.defaultv += "," + strings.Join(parts[i+1:], ",")
+			}
+			ft.hasDefault = true
+			return ft
+		}
+	}
+	return ft
+}
+
+// Inner state of an Unmarshal call.
+type binder struct {
+	c    *Config
+	s    *snapshot
+	keys []string
+
+	// The struct types being bound, by the enclosing bindStruct calls.
+	visiting map[reflect.Type]bool
+
+	problems []string
+}
+
+func (b *binder) bindStruct(prefix string, rv reflect.Value) {
+	rt := rv.Type()
+	b.visiting[rt] = true
+	defer delete(b.visiting, rt)
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		// The unexported fields are skipped, except the embedded structs
+		// whose exported fields are promoted.
+		if field.PkgPath != "" && !(field.Anonymous && field.Type.Kind() == reflect.Struct) {
+			continue
+		}
+
+		tag, tagged := field.Tag.Lookup(tagName)
+		if tag == "-" {
+			continue
+		}
+
+		fv := rv.Field(i)
+		ft := parseTag(tag)
+
+		if isGroup(field.Type) {
+			p := prefix
+			if tagged && ft.name != "" {
+				p = prefix + ft.name + "."
+			}
+			if fv.Kind() == reflect.Ptr {
+				// A pointer to a struct being bound is followed only if
+				// the group has keys, and never by an untagged field, so
+				// that a self-referential struct doesn't recurse forever.
+				if b.visiting[field.Type.Elem()] && (p == prefix || !b.hasPrefix(p)) {
+					continue
+				}
+				if fv.IsNil() {
+					if !fv.CanSet() {
+						continue
+					}
+					fv.Set(reflect.New(field.Type.Elem()))
+				}
+				fv = fv.Elem()
+			}
+			b.bindStruct(p, fv)
+			continue
+		}
+
+		if !tagged || ft.name == "" || !fv.CanSet() {
+			continue
+		}
+
+		key := prefix + ft.name
+		b.c.touch(key)
+		raw, ok, secret, err := b.s.getSecret(key)
+		if err != nil {
+			b.problems = append(b.problems, err.Message())
+			continue
+		}
+		if !ok && fv.Kind() == reflect.Slice {
+			// The "key[] = value" lines and the arrays are the elements.
+			if elements := b.s.elements(key); len(elements) > 0 {
+				b.bindList(fv, elements)
+				continue
+			}
+		}
+		if !ok {
+			if ft.required {
+				b.problems = append(b.problems, fmt.Sprintf("missing required key %s", key))
+				continue
+			}
+			if !ft.hasDefault {
+				continue
+			}
+			raw = ft.defaultv
+		}
+
+		if err := setValue(fv, raw); err != nil {
+			msg := err.Error()
+			if secret {
+				msg = fmt.Sprintf("cann't convert %s to %s", redacted, fv.Type())
+			}
+			b.problems = append(b.problems, fmt.Sprintf("key %s: %s", key, msg))
+		}
+	}
+}
+
+// Inner method, convert the elements of a list and store them into the slice v.
+func (b *binder) bindList(v reflect.Value, elements []string) {
+	s := reflect.MakeSlice(v.Type(), len(elements), len(elements))
+	for i, key := range elements {
+		b.c.touch(key)
+		raw, _, secret, err := b.s.getSecret(key)
+		if err != nil {
+			b.problems = append(b.problems, err.Message())
+			return
+		}
+		if err := setScalar(s.Index(i), raw); err != nil {
+			msg := err.Error()
+			if secret {
+				msg = fmt.Sprintf("cann't convert %s to %s", redacted, s.Index(i).Type())
+			}
+			b.problems = append(b.problems, fmt.Sprintf("key %s: %s", key, msg))
+			return
+		}
+	}
+	v.Set(s)
+}
+
+// Inner method, whether a key of the config starts with the prefix.
+func (b *binder) hasPrefix(prefix string) bool {
+	for _, k := range b.keys {
+		if strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Inner method, whether the type is a (pointer to) struct key group.
+func isGroup(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != timeType
+}
+
+// Inner method, convert raw and store it into v.
+func setValue(v reflect.Value, raw string) error {
+	if v.Kind() == reflect.Ptr {
+		elem := reflect.New(v.Type().Elem())
+		if err := setValue(elem.Elem(), raw); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+
+	if v.Kind() == reflect.Slice {
+		items, err := splitList(raw)
+		if err != nil {
+			return err
+		}
+		s := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setScalar(s.Index(i), item); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+
+	return setScalar(v, raw)
+}
+
+func setScalar(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("cann't convert %q to duration", raw)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	if v.Type() == timeType {
+		t, err := parseTime(raw)
+		if err != nil {
+			return fmt.Errorf("cann't convert %q to time", raw)
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, ok := parseBool(raw)
+		if !ok {
+			return fmt.Errorf("cann't convert %q to bool", raw)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := parseInt(raw, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("cann't convert %q to %s", raw, v.Type())
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := parseUint(raw, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("cann't convert %q to %s", raw, v.Type())
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("cann't convert %q to %s", raw, v.Type())
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type dbSettings struct {
+	Host    string        `conf:"host,default=localhost"`
+	Port    int           `conf:"port,required"`
+	Timeout time.Duration `conf:"timeout,default=3s"`
+	Slaves  []string      `conf:"slaves"`
+}
+
+type settings struct {
+	Name    string     `conf:"name"`
+	Age     uint8      `conf:"age"`
+	Height  float32    `conf:"height"`
+	Man     bool       `conf:"man"`
+	Ignored string     `conf:"-"`
+	DB      dbSettings `conf:"db"`
+	Cache   *struct {
+		Size []int `conf:"size,default=1,2,3"`
+	} `conf:"cache"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	conf := loadConfig(t)
+	conf.SetOption("db.port", "3306")
+	conf.SetOption("db.slaves", "a, b")
+	conf.SetOption("Ignored", "x")
+
+	var s settings
+	if err := Unmarshal(conf, &s); err != nil {
+		t.Fatalf("Expected unmarshal no error, but was %v", err.Message())
+	}
+
+	if s.Name != "tom" || s.Age != 25 || s.Height != 1.7 || !s.Man || s.Ignored != "" {
+		t.Errorf("Unexpected top level fields %+v", s)
+	}
+	if s.DB.Host != "localhost" || s.DB.Port != 3306 || s.DB.Timeout != 3*time.Second {
+		t.Errorf("Unexpected db fields %+v", s.DB)
+	}
+	if len(s.DB.Slaves) != 2 || s.DB.Slaves[0] != "a" || s.DB.Slaves[1] != "b" {
+		t.Errorf(`Expected db slaves to be ["a" "b"], but was %q`, s.DB.Slaves)
+	}
+	if s.Cache == nil || len(s.Cache.Size) != 3 || s.Cache.Size[2] != 3 {
+		t.Errorf("Expected cache size to be default [1 2 3], but was %v", s.Cache)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	conf := New()
+	conf.SetOption("age", "2o")
+	conf.SetOption("man", "maybe")
+	conf.SetOption("db.timeout", "soon")
+
+	var s settings
+	err := conf.Bind(&s)
+	if err == nil {
+		t.Fatal("Expected unmarshal error.")
+	}
+
+	msg := err.Message()
+	for _, want := range []string{"missing required key db.port", "key age", "key man", "key db.timeout"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Expected error to contain %q, but was %q", want, msg)
+		}
+	}
+
+	if Unmarshal(conf, s) == nil {
+		t.Error("Expected error on non pointer target.")
+	}
+}
+
+type node struct {
+	Name string `conf:"name"`
+	Next *node  `conf:"next"`
+}
+
+type loop struct {
+	Name string `conf:"name"`
+	*loop
+}
+
+func TestUnmarshalRecursive(t *testing.T) {
+	conf := New()
+	conf.SetOption("name", "a")
+	conf.SetOption("next.name", "b")
+	conf.SetOption("next.next.name", "c")
+
+	var n node
+	if err := conf.Bind(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n.Name != "a" || n.Next == nil || n.Next.Name != "b" || n.Next.Next == nil || n.Next.Next.Name != "c" {
+		t.Fatalf("Expected the linked nodes a, b, c, but was %+v", n)
+	}
+	if n.Next.Next.Next != nil {
+		t.Errorf("Expected the last node without next, but was %+v", n.Next.Next.Next)
+	}
+
+	var l loop
+	if err := conf.Bind(&l); err != nil || l.Name != "a" || l.loop != nil {
+		t.Errorf("Expected the untagged self pointer ignored, but was %+v %v", l, err)
+	}
+}
+
+type port int
+
+type inner struct {
+	Host string `conf:"host"`
+}
+
+type embedded struct {
+	port `conf:"port"`
+	*inner
+	dbSettings `conf:"db"`
+}
+
+func TestUnmarshalUnexportedEmbedded(t *testing.T) {
+	conf := New()
+	conf.SetOption("port", "80")
+	conf.SetOption("host", "localhost")
+	conf.SetOption("db.port", "3306")
+
+	var e embedded
+	if err := conf.Bind(&e); err != nil {
+		t.Fatal(err)
+	}
+	if e.port != 0 || e.inner != nil {
+		t.Errorf("Expected the unexported embedded fields skipped, but was %+v", e)
+	}
+	if e.dbSettings.Port != 3306 || e.dbSettings.Host != "localhost" {
+		t.Errorf("Expected the promoted fields bound, but was %+v", e.dbSettings)
+	}
+}
+
+func TestUnmarshalTime(t *testing.T) {
+	var s struct {
+		T  time.Time   `conf:"t"`
+		P  *time.Time  `conf:"p"`
+		Ts []time.Time `conf:"ts"`
+	}
+	conf := New()
+	conf.SetOption("t", "2024-01-02T03:04:05Z")
+	conf.SetOption("p", "2024-01-02T03:04:05Z")
+	conf.SetOption("ts", "2024-01-02T03:04:05Z, 2025-01-02T03:04:05Z")
+	if err := conf.Bind(&s); err != nil {
+		t.Fatal(err)
+	}
+	if s.T.Year() != 2024 || s.P == nil || s.P.Hour() != 3 || len(s.Ts) != 2 || s.Ts[1].Year() != 2025 {
+		t.Errorf("Expected the times bound, but was %+v", s)
+	}
+
+	conf.SetOption("t", "yesterday")
+	if err := conf.Bind(&s); err == nil || !strings.Contains(err.Message(), `key t: cann't convert "yesterday" to time`) {
+		t.Errorf("Expected the time error, but was %v", err)
+	}
+}
+
+func TestUnmarshalList(t *testing.T) {
+	var s struct {
+		Items []int    `conf:"items,required"`
+		Hosts []string `conf:"db.hosts"`
+	}
+	conf, err := LoadReader(strings.NewReader("items[] = 3\nitems[] = 1\nitems[] = 2\n"), "app.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Unmarshal(conf, &s); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Items) != 3 || s.Items[0] != 3 || s.Items[2] != 2 {
+		t.Errorf("Expected items [3 1 2], but was %v", s.Items)
+	}
+
+	conf, err = parseJSON([]byte(`{"items": [80, 443], "db": {"hosts": ["a", "b"]}}`), "app.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Unmarshal(conf, &s); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Items) != 2 || s.Items[1] != 443 || len(s.Hosts) != 2 || s.Hosts[1] != "b" {
+		t.Errorf("Expected the arrays bound, but was %+v", s)
+	}
+
+	conf.SetOption("items[1]", "x")
+	if err := Unmarshal(conf, &s); err == nil || !strings.Contains(err.Message(), "key items[1]:") {
+		t.Errorf("Expected the element error, but was %v", err)
+	}
+}
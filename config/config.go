@@ -5,171 +5,458 @@
 package config
 
 import (
-	"bufio"
 	"github.com/roverli/utils/errors"
 	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 // Read options from the file.
+//
+// The file contains "key = value" lines, blank lines and "#" comments.
+// A "[section]" header line starts a section, the keys after it are
+// prefixed by the section name, e.g. "host" in "[database]" is "database.host".
+//
+// An "include path" line loads another file at that point, path is relative
+// to the including file and may be a glob pattern such as "conf.d/*.conf".
+// The matched files are loaded in name order, and the later definitions of
+// a key override the earlier ones.
+//
+// The "[profile:name]" blocks and "key@name" lines overlay the base options
+// for the profiles in the APP_PROFILE environment variable, see LoadProfile.
+// The repeated "key[] = value" lines are the elements of a list, see List.
+//
+// The registered migrations are applied to the loaded options, see Migrations.
 func Load(fname string) (*Config, errors.Error) {
 	file, err := os.Open(fname)
 	if err != nil {
 		return nil, errors.Wrapf(err, "cann't open file %s", fname)
 	}
+	defer file.Close()
 
-	reader := bufio.NewReader(file)
-	options := make(map[string]string)
-	for {
-
-		line, err := reader.ReadString('\n')
-
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return nil, errors.Wrapf(err, "read file %s error.", fname)
-		}
+	return LoadReader(file, fname)
+}
 
-		line = strings.TrimSpace(line)
+// LoadReader reads options from r in the format of Load, name is used in the
+// error messages and positions. The include paths are relative to the
+// directory of name in the OS file system, e.g. the current directory for
+// a name like "<stdin>".
+func LoadReader(r io.Reader, name string) (*Config, errors.Error) {
+	return parseConfig(newParser(nil), r, name, envProfiles())
+}
 
-		if len(line) == 0 || line[0] == '#' {
-			continue
-		}
+// LoadFS reads options from the file in fsys in the format of Load, e.g. from
+// an embed.FS. The include paths are resolved in fsys too, an absolute
+// include path is relative to the root of fsys.
+func LoadFS(fsys fs.FS, path string) (*Config, errors.Error) {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cann't open file %s", path)
+	}
+	defer file.Close()
 
-		i := strings.Index(line, "=")
-		if i < 0 {
-			return nil, errors.New("parse error: " + line)
-		}
+	return parseConfig(newParser(fsys), file, path, envProfiles())
+}
 
-		options[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+// Inner method, parse the content of r with p.
+// The profiles are activated in order, see LoadProfile.
+func parseConfig(p *parser, r io.Reader, name string, names []string) (*Config, errors.Error) {
+	lines, err := p.parseReader(r, name)
+	if err != nil {
+		return nil, err
 	}
+	profiles := p.apply(names)
 
-	if err = file.Close(); err != nil {
-		return nil, errors.Wrapf(err, "close file %s error.", fname)
-	}
+	c := newConfig(&snapshot{options: p.options, positions: p.positions})
+	c.lines = lines
+	c.profiles = profiles
+	return migrated(c, nil)
+}
 
-	return &Config{options: options}, nil
+// LoadAny reads options from the file, the format is detected from the
+// file extension:
+//
+//	.json          LoadJSON
+//	.toml          LoadTOML
+//	.properties    LoadProperties
+//	.env           LoadDotenv
+//	others         Load
+func LoadAny(fname string) (*Config, errors.Error) {
+	switch strings.ToLower(filepath.Ext(fname)) {
+	case ".json":
+		return LoadJSON(fname)
+	case ".toml":
+		return LoadTOML(fname)
+	case ".properties":
+		return LoadProperties(fname)
+	case ".env":
+		return LoadDotenv(fname)
+	}
+	return Load(fname)
 }
 
 // Return a config instance with empty options.
 func New() *Config {
-	return &Config{options: make(map[string]string)}
+	return newConfig(&snapshot{
+		options:   make(map[string]string),
+		positions: make(map[string]position),
+	})
+}
+
+// Inner method, create a config with the snapshot.
+func newConfig(s *snapshot) *Config {
+	c := &Config{accessed: &sync.Map{}}
+	c.snap.Store(s)
+	return c
 }
 
-// Config is not safe for multiply goroutines access.
+// Config is safe for multiply goroutines access.
+//
+// The options are kept in an immutable snapshot. The readers get the current
+// snapshot without locking, the writers copy it, modify the copy and publish
+// it atomically. So a write costs O(n), Config is designed for read mostly use.
 type Config struct {
+	// The current *snapshot.
+	snap atomic.Value
+
+	// Serializes the writers.
+	mu sync.Mutex
+
+	// The lines of the loaded file, used to write the config back.
+	lines []line
+
+	// The active profiles of the loaded file.
+	profiles []string
+
+	// The sources of a Layered config, used by Explain.
+	layers []Source
+
+	// The keys read by the getters, see UnusedKeys. A Sub config shares the
+	// tracker of its parent and records the keys with the section prefix.
+	accessed *sync.Map
+	prefix   string
+
+	// The warnings of the applied migrations, guarded by mu.
+	warnings []Warning
+}
+
+// Inner immutable options of a config, never modified after published.
+type snapshot struct {
 	options map[string]string
+
+	// The file and line where the loaded keys were defined.
+	positions map[string]position
+
+	// The env mapping, nil is the default raw key fallback.
+	env *envMapping
+
+	// Decrypts the ENC(...) values.
+	provider KeyProvider
+
+	// The values of the flags set on the command line, they override the
+	// options and the env, see BindFlags. Never modified, replaced on write.
+	flags map[string]string
+}
+
+// Inner method, return the current snapshot.
+func (c *Config) load() *snapshot {
+	return c.snap.Load().(*snapshot)
+}
+
+// Inner method, apply f on a copy of the current snapshot and publish the copy.
+func (c *Config) update(f func(s *snapshot)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	old := c.load()
+	s := &snapshot{
+		options:   make(map[string]string, len(old.options)),
+		positions: make(map[string]position, len(old.positions)),
+		env:       old.env,
+		provider:  old.provider,
+		flags:     old.flags,
+	}
+	for k, v := range old.options {
+		s.options[k] = v
+	}
+	for k, p := range old.positions {
+		s.positions[k] = p
+	}
+	f(s)
+	c.snap.Store(s)
+}
+
+// Snapshot returns a config with the current options, it is not affected by
+// the later writes on c. Use it to read several keys consistently.
+// It's cheap, the options are shared until one of the configs is modified.
+func (c *Config) Snapshot() *Config {
+	sc := newConfig(c.load())
+	sc.lines = c.lines
+	sc.profiles = c.profiles
+	sc.accessed = c.accessed
+	sc.prefix = c.prefix
+	sc.layers = c.layers
+	sc.warnings = c.Warnings()
+	return sc
 }
 
 // IsEmpty check whether the configuration is empty.
 // Return true if the configuration contains no property, false otherwise.
 func (c *Config) IsEmpty() bool {
-	return len(c.options) == 0
+	return len(c.load().options) == 0
 }
 
 // Remove all options from the configuration.
 func (c *Config) Clear() {
-	c.options = make(map[string]string)
+	c.update(func(s *snapshot) {
+		s.options = make(map[string]string)
+		s.positions = make(map[string]position)
+	})
 }
 
 // Remove a option from the configuration.
 func (c *Config) ClearOption(key string) {
-	delete(c.options, key)
+	c.update(func(s *snapshot) {
+		delete(s.options, key)
+		delete(s.positions, key)
+	})
 }
 
 // Set a option, this will replace any previously set values.
 // If previous value does not exist, eq to add a new option.
 func (c *Config) SetOption(key string, value string) {
-	c.options[key] = value
+	c.update(func(s *snapshot) {
+		s.options[key] = value
+		delete(s.positions, key)
+	})
 }
 
 // Get the list of the keys contained in the configuration.
 // The returned slice can be used to obtain all defined keys.
+// The keys mapped from the environment variables are included, see SetEnvPrefix.
 func (c *Config) Keys() []string {
-	i := 0
-	keys := make([]string, len(c.options))
-	for k, _ := range c.options {
-		keys[i] = k
-		i++
+	return c.load().keys()
+}
+
+// Sections gets the sorted distinct section names in the configuration.
+// A section is the part of a key before the first ".", so both the keys
+// under a "[database]" header and the "database.host" style keys are listed.
+func (c *Config) Sections() []string {
+	seen := make(map[string]bool)
+	sections := []string{}
+	for k := range c.load().options {
+		i := strings.Index(k, ".")
+		if i <= 0 || seen[k[:i]] {
+			continue
+		}
+		seen[k[:i]] = true
+		sections = append(sections, k[:i])
+	}
+	sort.Strings(sections)
+	return sections
+}
+
+// Sub returns a new config scoped to the section, the keys in it have the
+// "section." prefix removed. Nested sections such as "a.b" are supported.
+// The returned config is a copy, changes on it do not affect c. The keys read
+// from it are counted as read from c by UnusedKeys.
+func (c *Config) Sub(section string) *Config {
+	prefix := section + "."
+	s := c.load()
+	sub := &snapshot{
+		options:   make(map[string]string),
+		positions: make(map[string]position),
+		env:       s.env,
+		provider:  s.provider,
+	}
+	// The env keys of the section are mapped under the section prefix.
+	if s.mapped() {
+		sub.env = &envMapping{prefix: envName(s.env.prefix, section) + "_"}
+	}
+	for k, v := range s.options {
+		if strings.HasPrefix(k, prefix) && len(k) > len(prefix) {
+			sub.options[k[len(prefix):]] = v
+			if p, ok := s.positions[k]; ok {
+				sub.positions[k[len(prefix):]] = p
+			}
+		}
+	}
+	for k, v := range s.flags {
+		if strings.HasPrefix(k, prefix) && len(k) > len(prefix) {
+			if sub.flags == nil {
+				sub.flags = make(map[string]string)
+			}
+			sub.flags[k[len(prefix):]] = v
+		}
 	}
-	return keys
+	sc := newConfig(sub)
+	sc.accessed = c.accessed
+	sc.prefix = c.prefix + prefix
+	return sc
 }
 
 // String gets the string value for the given key in the configuration.
-// It returns default value if the key does not exist.
+// The "${...}" references in the value are expanded.
+// It returns default value if the key does not exist or cann't be expanded.
 func (c *Config) String(key string, defaultv string) string {
-	if v, ok := c.options[key]; ok {
-		return v
+	v, _ := c.StringE(key, defaultv)
+	return v
+}
+
+// StringE is like String, but returns the error if the value cann't be expanded,
+// e.g. it contains a reference cycle or an undefined reference.
+func (c *Config) StringE(key string, defaultv string) (string, errors.Error) {
+	c.touch(key)
+	v, ok, err := c.load().get(key)
+	if !ok || err != nil {
+		return defaultv, err
 	}
+	return v, nil
+}
 
-	// Support get config from env.
-	v := os.Getenv(key)
-	if v != "" {
-		return v
+// Inner method, find the raw value of the key.
+// The second return value is false if the key does not exist.
+// The env overrides the options under the structured mapping, otherwise
+// it's the fallback of the missing keys, see SetEnvPrefix. The flags
+// override both, see BindFlags.
+func (s *snapshot) lookup(key string) (string, bool) {
+	if v, ok := s.flags[key]; ok {
+		return v, true
+	}
+	if s.mapped() {
+		if v, ok := s.lookupEnv(key); ok {
+			return v, true
+		}
 	}
-	return defaultv
+	if v, ok := s.options[key]; ok {
+		return v, true
+	}
+	if !s.mapped() {
+		return s.lookupEnv(key)
+	}
+	return "", false
 }
 
 // Bool gets the bool value for the given key in the configuration.
+// The true values are "y", "on", "true" and "1", the false values are "n",
+// "off", "false" and "0", case insensitive. "true" and "false" are the bools
+// of LoadJSON and LoadTOML.
 // It returns default value if the key does not exist or cann't be converted to bool.
 func (c *Config) Bool(key string, defaultv bool) bool {
-	switch strings.ToLower(c.String(key, "")) {
-	case "y", "on", "1":
-		return true
-	case "n", "off", "0":
-		return false
+	v, _ := c.BoolE(key, defaultv)
+	return v
+}
+
+// BoolE is like Bool, but returns the error if the value cann't be converted to bool.
+func (c *Config) BoolE(key string, defaultv bool) (bool, errors.Error) {
+	b := defaultv
+	err := c.convert(key, "bool", func(v string) error {
+		var ok bool
+		if b, ok = parseBool(v); !ok {
+			return strconv.ErrSyntax
+		}
+		return nil
+	})
+	if err != nil {
+		return defaultv, err
+	}
+	return b, nil
+}
+
+// Inner method, convert the config style bool literal to bool.
+// The second return value is false if s is not a bool literal.
+func parseBool(s string) (bool, bool) {
+	switch strings.ToLower(s) {
+	case "y", "on", "true", "1":
+		return true, true
+	case "n", "off", "false", "0":
+		return false, true
 	default:
-		return defaultv
+		return false, false
 	}
 }
 
 // Float gets the float value for the given key in the configuration.
 // It returns default value if the key does not exist or cann't be converted to float64.
 func (c *Config) Float(key string, defaultv float64) float64 {
-	v, err := strconv.ParseFloat(c.String(key, ""), 64)
-	if err == nil {
-		return v
-	} else {
-		return defaultv
+	v, _ := c.FloatE(key, defaultv)
+	return v
+}
+
+// FloatE is like Float, but returns the error if the value cann't be converted to float64.
+func (c *Config) FloatE(key string, defaultv float64) (float64, errors.Error) {
+	f := defaultv
+	err := c.convert(key, "float64", func(v string) (e error) {
+		f, e = strconv.ParseFloat(v, 64)
+		return
+	})
+	if err != nil {
+		return defaultv, err
 	}
+	return f, nil
 }
 
 // Int gets the int value for the given key in the configuration.
+// Hex, octal and binary literals with "0x", "0o" and "0b" prefix and
+// "_" digit separators are supported, e.g. "0xff", "1_000".
 // It returns default value if the key does not exist or cann't be converted to int.
 func (c *Config) Int(key string, defaultv int) int {
-	v, err := strconv.Atoi(c.String(key, ""))
-	if err == nil {
-		return v
-	} else {
-		return defaultv
+	v, _ := c.IntE(key, defaultv)
+	return v
+}
+
+// IntE is like Int, but returns the error if the value cann't be converted to int.
+func (c *Config) IntE(key string, defaultv int) (int, errors.Error) {
+	n := int64(defaultv)
+	err := c.convert(key, "int", func(v string) (e error) {
+		n, e = parseInt(v, strconv.IntSize)
+		return
+	})
+	if err != nil {
+		return defaultv, err
 	}
+	return int(n), nil
 }
 
 // Merge the target config options in current config.
 // If target config has the same key with current config, the value was ignored.
+// A key supplied by the env of current config counts as existing.
 func (c *Config) Merge(target *Config) {
-	kvs := target.toKvs()
+	ts := target.load()
+	kvs := ts.toKvs()
 
-	for _, kv := range kvs {
-		k := kv[0]
-		v := kv[1]
-		if _, ok := c.options[k]; !ok {
-			c.options[k] = v
+	c.update(func(s *snapshot) {
+		for _, kv := range kvs {
+			k := kv[0]
+			v := kv[1]
+			if _, ok := s.lookup(k); !ok {
+				s.options[k] = v
+				p := ts.positions[k]
+				p.merged = true
+				s.positions[k] = p
+			}
 		}
-	}
+	})
 }
 
 // Inner method, for "Merge" method.
-func (c *Config) toKvs() [][2]string {
-
-	i := 0
-	kvs := make([][2]string, len(c.options))
-	for k, v := range c.options {
-		kvs[i] = [2]string{k, v}
-		i++
+// The env values of the target are included under the structured mapping,
+// and the flag values are included.
+func (s *snapshot) toKvs() [][2]string {
+	keys := s.keys()
+	kvs := make([][2]string, 0, len(keys))
+	for _, k := range keys {
+		v := s.options[k]
+		if s.mapped() || len(s.flags) > 0 {
+			v, _ = s.lookup(k)
+		}
+		kvs = append(kvs, [2]string{k, v})
 	}
 	return kvs
 }
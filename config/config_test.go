@@ -19,7 +19,7 @@ func loadConfig(t *testing.T) *Config {
 func TestLoad(t *testing.T) {
 	conf := loadConfig(t)
 
-	if len(conf.options) != 4 {
+	if len(conf.Keys()) != 4 {
 		t.Error("Expected config only has 4 options.")
 	}
 
@@ -115,7 +115,7 @@ func TestMerge(t *testing.T) {
 	conf2.SetOption("city", "Tokyo")
 
 	conf1.Merge(conf2)
-	if len(conf1.options) != 5 {
+	if len(conf1.Keys()) != 5 {
 		t.Error("Expected conf1 len to be 5.")
 	}
 
@@ -128,3 +128,43 @@ func TestMerge(t *testing.T) {
 		t.Error(`Expected city to be "Tokyo".But was %v`, city)
 	}
 }
+
+func TestLoadSection(t *testing.T) {
+	conf, err := Load("testdata/section.conf")
+	if err != nil {
+		t.Fatalf("Expected load section.conf no error, but was %v", err.Message())
+	}
+
+	if v := conf.String("name", ""); v != "app" {
+		t.Errorf(`Expected name to be "app", but was %v`, v)
+	}
+	if v := conf.String("database.host", ""); v != "db.local" {
+		t.Errorf(`Expected database.host to be "db.local", but was %v`, v)
+	}
+	if v := conf.String("cache.redis.addr", ""); v != "127.0.0.1:6379" {
+		t.Errorf(`Expected cache.redis.addr to be "127.0.0.1:6379", but was %v`, v)
+	}
+
+	sections := conf.Sections()
+	if len(sections) != 2 || sections[0] != "cache" || sections[1] != "database" {
+		t.Errorf(`Expected sections to be ["cache" "database"], but was %q`, sections)
+	}
+
+	db := conf.Sub("database")
+	if len(db.Keys()) != 2 || db.Int("port", -1) != 3306 {
+		t.Errorf("Expected database sub config has host and port, but was %v", db.Keys())
+	}
+
+	redis := conf.Sub("cache").Sub("redis")
+	if v := redis.String("addr", ""); v != "127.0.0.1:6379" {
+		t.Errorf(`Expected cache.redis sub config addr to be "127.0.0.1:6379", but was %v`, v)
+	}
+}
+
+func TestLoadBadSection(t *testing.T) {
+	for _, line := range []string{"[]", "[database", "[a=b]"} {
+		if _, ok := parseSection(line); ok {
+			t.Errorf("Expected %q to be an invalid section header.", line)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/roverli/utils/errors"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Change is a changed key of Diff. Old is empty for an added key, New is
+// empty for a removed key. The secret values are redacted.
+type Change struct {
+	Key    string `json:"key"`
+	Old    string `json:"old"`
+	New    string `json:"new"`
+	Secret bool   `json:"secret,omitempty"`
+}
+
+// Changes is the result of Diff, each list is sorted by key.
+type Changes struct {
+	Added   []Change `json:"added"`
+	Removed []Change `json:"removed"`
+	Changed []Change `json:"changed"`
+}
+
+// Diff compares the options of a and b, the raw values are compared, so a
+// changed reference target doesn't change the keys referencing it. The keys
+// mapped from the env are included as Keys.
+//
+// The secrets are compared by the decrypted values, so an ENC(...) value
+// encrypted again is not a change, and they are redacted in the result.
+func Diff(a, b *Config) *Changes {
+	as, bs := a.load(), b.load()
+	old, new := kvMap(as), kvMap(bs)
+
+	d := &Changes{Added: []Change{}, Removed: []Change{}, Changed: []Change{}}
+	for _, k := range sortedKeys(old, new) {
+		ov, inOld := old[k]
+		nv, inNew := new[k]
+		ov, oldSecret := diffValue(as, k, ov)
+		nv, newSecret := diffValue(bs, k, nv)
+		secret := oldSecret || newSecret
+
+		switch {
+		case !inOld:
+			d.Added = append(d.Added, newChange(k, "", nv, secret))
+		case !inNew:
+			d.Removed = append(d.Removed, newChange(k, ov, "", secret))
+		case ov != nv:
+			d.Changed = append(d.Changed, newChange(k, ov, nv, secret))
+		}
+	}
+	return d
+}
+
+// Inner method, return the key value map of the options.
+func kvMap(s *snapshot) map[string]string {
+	kvs := s.toKvs()
+	m := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		m[kv[0]] = kv[1]
+	}
+	return m
+}
+
+// Inner method, return the sorted keys of the maps.
+func sortedKeys(maps ...map[string]string) []string {
+	seen := make(map[string]bool)
+	keys := []string{}
+	for _, m := range maps {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Inner method, return the value to compare and whether it's a secret.
+// The secret is compared by the decrypted value if it can be decrypted.
+func diffValue(s *snapshot, key string, raw string) (string, bool) {
+	if _, _, secret, _ := s.getSecret(key); !secret {
+		return raw, false
+	}
+	if v, ok, _, err := s.fetch(key); ok && err == nil {
+		return v, true
+	}
+	return raw, true
+}
+
+func newChange(key, old, new string, secret bool) Change {
+	if secret {
+		if old != "" {
+			old = redacted
+		}
+		if new != "" {
+			new = redacted
+		}
+	}
+	return Change{Key: key, Old: old, New: new, Secret: secret}
+}
+
+// IsEmpty checks whether there is no change.
+func (d *Changes) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Keys gets the sorted keys of all the changes.
+func (d *Changes) Keys() []string {
+	keys := []string{}
+	for _, list := range [][]Change{d.Added, d.Removed, d.Changed} {
+		for _, c := range list {
+			keys = append(keys, c.Key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// String renders the changes in the key order, one per line, "+" for the
+// added keys, "-" for the removed keys and "~" for the changed keys, e.g.
+//
+//	~ db.host: localhost -> db.prod
+//	- db.slave = 10.0.0.2
+//	+ db.user = root
+func (d *Changes) String() string {
+	lines := make(map[string]string)
+	for _, c := range d.Added {
+		lines[c.Key] = fmt.Sprintf("+ %s = %s", c.Key, c.New)
+	}
+	for _, c := range d.Removed {
+		lines[c.Key] = fmt.Sprintf("- %s = %s", c.Key, c.Old)
+	}
+	for _, c := range d.Changed {
+		lines[c.Key] = fmt.Sprintf("~ %s: %s -> %s", c.Key, c.Old, c.New)
+	}
+
+	var b strings.Builder
+	for _, k := range d.Keys() {
+		b.WriteString(lines[k] + "\n")
+	}
+	return b.String()
+}
+
+// WriteJSON writes the changes to w as a JSON object with the "added",
+// "removed" and "changed" lists.
+func (d *Changes) WriteJSON(w io.Writer) errors.Error {
+	content, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "write json error.")
+	}
+	if _, err = w.Write(append(content, '\n')); err != nil {
+		return errors.Wrap(err, "write json error.")
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a := New()
+	a.SetOption("db.host", "localhost")
+	a.SetOption("db.port", "3306")
+	a.SetOption("db.slave", "10.0.0.2")
+
+	b := New()
+	b.SetOption("db.host", "db.prod")
+	b.SetOption("db.port", "3306")
+	b.SetOption("db.user", "root")
+
+	d := Diff(a, b)
+	if v := d.Keys(); !reflect.DeepEqual(v, []string{"db.host", "db.slave", "db.user"}) {
+		t.Errorf("Expected changed keys, but was %v", v)
+	}
+	expected := "~ db.host: localhost -> db.prod\n- db.slave = 10.0.0.2\n+ db.user = root\n"
+	if d.String() != expected {
+		t.Errorf("Expected diff:\n%s\nbut was:\n%s", expected, d.String())
+	}
+
+	if !Diff(a, a.Snapshot()).IsEmpty() {
+		t.Errorf("Expected no changes on the same options")
+	}
+}
+
+func TestDiffSecret(t *testing.T) {
+	p, err := NewAESKeyProvider(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypt := func(v string) string {
+		e, _ := p.EncryptValue(v)
+		return e
+	}
+
+	a := New()
+	a.SetKeyProvider(p)
+	a.SetOption("db.password", encrypt("old"))
+	a.SetOption("api.token", encrypt("token"))
+
+	b := New()
+	b.SetKeyProvider(p)
+	b.SetOption("db.password", encrypt("new"))
+	b.SetOption("api.token", encrypt("token"))
+	b.SetOption("api.key", encrypt("key"))
+
+	d := Diff(a, b)
+	expected := &Changes{
+		Added:   []Change{{Key: "api.key", New: "******", Secret: true}},
+		Removed: []Change{},
+		Changed: []Change{{Key: "db.password", Old: "******", New: "******", Secret: true}},
+	}
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("Expected %+v, but was %+v", expected, d)
+	}
+
+	var buf bytes.Buffer
+	if err := d.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Changes
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Expected valid json, but was %v:\n%s", err, buf.String())
+	}
+	if !reflect.DeepEqual(&decoded, expected) {
+		t.Errorf("Expected json round trip %+v, but was %+v", expected, decoded)
+	}
+	if bytes.Contains(buf.Bytes(), []byte(`: "old"`)) || bytes.Contains(buf.Bytes(), []byte(`: "new"`)) {
+		t.Errorf("Expected redacted json, but was %s", buf.String())
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bufio"
+	"github.com/roverli/utils/errors"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// LoadDotenv reads options from the .env file.
+//
+// The file contains "KEY=value" lines with an optional "export " prefix,
+// blank lines and "#" comments. A value is either:
+//
+//	unquoted       trimmed, " #" starts an inline comment
+//	'single'       literal, no escapes
+//	"double"       "\n", "\r", "\t", "\"", "\\" and "\$" escapes
+//
+// Quoted values may span multiple lines. The "${...}" references are expanded
+// in the unquoted and double quoted values, a "\${" in a double quoted value
+// and all the "${" in a single quoted value are literal.
+func LoadDotenv(fname string) (*Config, errors.Error) {
+	content, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cann't open file %s", fname)
+	}
+	return migrated(parseDotenv(string(content), fname))
+}
+
+// Inner method, parse the .env content, name is used in the error messages.
+func parseDotenv(content string, name string) (*Config, errors.Error) {
+	s := &snapshot{
+		options:   make(map[string]string),
+		positions: make(map[string]position),
+	}
+
+	content = strings.Replace(content, "\r\n", "\n", -1)
+	n := 1
+	for len(content) > 0 {
+		start := n
+		var text string
+		if i := strings.IndexByte(content, '\n'); i >= 0 {
+			text, content = content[:i], content[i+1:]
+		} else {
+			text, content = content, ""
+		}
+		n++
+
+		// Keep the trailing spaces, they may be in a multi-line quoted value.
+		text = strings.TrimLeft(text, " \t")
+		if strings.TrimSpace(text) == "" || text[0] == '#' {
+			continue
+		}
+		if strings.HasPrefix(text, "export ") || strings.HasPrefix(text, "export\t") {
+			text = strings.TrimSpace(text[len("export"):])
+		}
+
+		i := strings.IndexByte(text, '=')
+		if i <= 0 || strings.ContainsAny(strings.TrimSpace(text[:i]), " \t") {
+			return nil, errors.Newf("parse error: %s (%s:%d)", text, name, start)
+		}
+		key := strings.TrimSpace(text[:i])
+		value := strings.TrimLeft(text[i+1:], " \t")
+
+		if value != "" && (value[0] == '"' || value[0] == '\'') {
+			// The quoted value may continue on the following lines.
+			v, rest, lines, ok := readQuoted(value+"\n"+content, value[0])
+			if !ok {
+				return nil, errors.Newf("parse error: unterminated quoted value of %s (%s:%d)", key, name, start)
+			}
+			if rest = strings.TrimLeft(rest, " \t"); rest != "" && rest[0] != '\n' && rest[0] != '#' {
+				return nil, errors.Newf("parse error: unexpected %q after quoted value of %s (%s:%d)",
+					strings.SplitN(rest, "\n", 2)[0], key, name, start)
+			}
+			if i := strings.IndexByte(rest, '\n'); i >= 0 {
+				content = rest[i+1:]
+			} else {
+				content = ""
+			}
+			n += lines
+			value = v
+		} else if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		} else if i := strings.Index(value, "\t#"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		} else {
+			value = strings.TrimSpace(value)
+		}
+
+		s.options[key] = value
+		s.positions[key] = position{file: name, line: start}
+	}
+	return newConfig(s), nil
+}
+
+// Inner method, read the value quoted by q at the start of text.
+// Return the unquoted value, the text after the closing quote, the number of
+// line breaks inside the quotes, and false if the quote is not closed.
+func readQuoted(text string, q byte) (string, string, int, bool) {
+	var b strings.Builder
+	lines := 0
+	for i := 1; i < len(text); i++ {
+		ch := text[i]
+		switch {
+		case ch == q:
+			v := b.String()
+			if q == '\'' {
+				// Keep the literal "${" from the expansion.
+				v = strings.Replace(v, "${", "$${", -1)
+			}
+			return v, text[i+1:], lines, true
+		case ch == '\\' && q == '"' && i+1 < len(text):
+			i++
+			switch text[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\':
+				b.WriteByte(text[i])
+			case '$':
+				// An escaped "${" is literal, see expand.
+				if i+1 < len(text) && text[i+1] == '{' {
+					b.WriteByte('$')
+				}
+				b.WriteByte('$')
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(text[i])
+			}
+		default:
+			if ch == '\n' {
+				lines++
+			}
+			b.WriteByte(ch)
+		}
+	}
+	return "", "", 0, false
+}
+
+// ApplyToEnv sets the options as environment variables, the "${...}"
+// references in the values are expanded. As the dotenv convention, the
+// variables which are already set in the environment are not overridden.
+func (c *Config) ApplyToEnv() errors.Error {
+	s := c.load()
+	for k := range s.options {
+		if _, ok := os.LookupEnv(k); ok {
+			continue
+		}
+		v, _, err := s.get(k)
+		if err != nil {
+			return err
+		}
+		if err := os.Setenv(k, v); err != nil {
+			return errors.Wrapf(err, "cann't set env %s", k)
+		}
+	}
+	return nil
+}
+
+// WriteDotenv writes the options to w in the .env format, the keys are
+// written in order. The values with spaces or special chars are double quoted.
+func (c *Config) WriteDotenv(w io.Writer) errors.Error {
+	s := c.load()
+	keys := make([]string, 0, len(s.options))
+	for k := range s.options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	bw := bufio.NewWriter(w)
+	for _, k := range keys {
+		bw.WriteString(k + "=" + quoteDotenv(s.options[k]) + "\n")
+	}
+	if err := bw.Flush(); err != nil {
+		return errors.Wrap(err, "write dotenv error.")
+	}
+	return nil
+}
+
+// Inner method, double quote the value if it's needed.
+func quoteDotenv(v string) string {
+	if !strings.ContainsAny(v, " \t\r\n#'\"\\$") {
+		return v
+	}
+	// The literal "$${" is written as "\${", a "${" reference is kept.
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$${`, `\${`, `${`, `${`, `$`, `\$`,
+		"\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + r.Replace(v) + `"`
+}
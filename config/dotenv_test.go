@@ -0,0 +1,148 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLoadDotenv(t *testing.T) {
+	conf, err := LoadDotenv("testdata/app.env")
+	if err != nil {
+		t.Fatalf("Expected load app.env no error, but was %v", err.Message())
+	}
+
+	cases := map[string]string{
+		"DB_HOST":  "localhost",
+		"DB_PORT":  "5432",
+		"NAME":     "Tom # not a comment",
+		"GREETING": "Hello\n\"World\" $HOME",
+		"RAW":      `a\nb`,
+		"MULTI":    "line one\nline two",
+		"PEM":      "-----BEGIN-----\nabc\n-----END-----",
+		"EMPTY":    "",
+		"URL":      "http://example.com/#anchor",
+		"AFTER":    "1",
+	}
+	for k, expected := range cases {
+		if v, ok := conf.load().lookup(k); !ok || v != expected {
+			t.Errorf("Expected %s to be %q, but was %q", k, expected, v)
+		}
+	}
+	if len(conf.Keys()) != len(cases) {
+		t.Errorf("Expected %d keys, but was %q", len(cases), conf.Keys())
+	}
+	if v := conf.Explain("AFTER"); v != "AFTER = 1 (testdata/app.env:14)" {
+		t.Errorf("Expected AFTER defined at line 14, but was %s", v)
+	}
+}
+
+func TestDotenvErrors(t *testing.T) {
+	cases := map[string]string{
+		"A=1\nB=\"open\n": "unterminated",
+		"A='x' y\n":       `unexpected "y"`,
+		"NO VALUE\n":      "parse error",
+		"export A B=1\n":  "parse error",
+		"=1\n":            "parse error",
+	}
+	for content, want := range cases {
+		_, err := parseDotenv(content, "bad.env")
+		if err == nil || !strings.Contains(err.Message(), want) {
+			t.Errorf("Expected %q to fail with %q, but was %v", content, want, err)
+		}
+	}
+}
+
+func TestDotenvRoundTrip(t *testing.T) {
+	conf, err := LoadDotenv("testdata/app.env")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := conf.WriteDotenv(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "DB_PORT=5432\n") ||
+		!strings.Contains(buf.String(), `GREETING="Hello\n\"World\" \$HOME"`+"\n") {
+		t.Errorf("Unexpected written dotenv:\n%s", buf.String())
+	}
+
+	again, err := parseDotenv(buf.String(), "buffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range conf.Keys() {
+		v1, _ := conf.load().lookup(k)
+		v2, _ := again.load().lookup(k)
+		if v1 != v2 {
+			t.Errorf("Expected %s to round trip as %q, but was %q", k, v1, v2)
+		}
+	}
+}
+
+func TestApplyToEnv(t *testing.T) {
+	t.Setenv("CONFIG_TEST_KEEP", "shell")
+	t.Setenv("CONFIG_TEST_SET", "")
+	os.Unsetenv("CONFIG_TEST_SET")
+
+	conf := New()
+	conf.SetOption("CONFIG_TEST_KEEP", "dotenv")
+	conf.SetOption("CONFIG_TEST_HOME", "/opt")
+	conf.SetOption("CONFIG_TEST_SET", "${CONFIG_TEST_HOME}/app")
+	if err := conf.ApplyToEnv(); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv("CONFIG_TEST_HOME")
+
+	if v := os.Getenv("CONFIG_TEST_KEEP"); v != "shell" {
+		t.Errorf("Expected existing env not overridden, but was %q", v)
+	}
+	if v := os.Getenv("CONFIG_TEST_SET"); v != "/opt/app" {
+		t.Errorf("Expected expanded env CONFIG_TEST_SET, but was %q", v)
+	}
+}
+
+func TestDotenvLiteralReferences(t *testing.T) {
+	content := "X=1\n" +
+		"A=\"p\\${x}\"\n" +
+		"B='lit${y} $${z}'\n" +
+		"C=\"ref ${X}\"\n" +
+		"D=${X}\n"
+	conf, err := parseDotenv(content, "literal.env")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"A": "p${x}",
+		"B": "lit${y} $${z}",
+		"C": "ref 1",
+		"D": "1",
+	}
+	for k, expected := range cases {
+		if v, err := conf.StringE(k, ""); err != nil || v != expected {
+			t.Errorf("Expected %s to be %q, but was %q %v", k, expected, v, err)
+		}
+	}
+
+	// The literals and references are kept when written.
+	var buf bytes.Buffer
+	if err := conf.WriteDotenv(&buf); err != nil {
+		t.Fatal(err)
+	}
+	again, err := parseDotenv(buf.String(), "buffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, expected := range cases {
+		if v, err := again.StringE(k, ""); err != nil || v != expected {
+			t.Errorf("Expected %s to round trip as %q, but was %q %v in:\n%s", k, expected, v, err, buf.String())
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// By default a key which is not in the options falls back to the environment
+// variable named by the raw key, e.g. String("HOME", "") reads $HOME. The env
+// can't override the keys such as "db.host", as the variable names can't
+// contain ".".
+//
+// SetEnvPrefix enables the structured mapping, the variable name of a key is
+// the prefix followed by the upper case key, with "." and "-" replaced by "_",
+// e.g. "APP_" and "db.host" is "APP_DB_HOST". Then:
+//
+//   - the env overrides the options, a set variable wins over the file
+//   - Keys lists the keys of the variables with the prefix, the variable name
+//     is mapped back to the option key with the same variable name, or else to
+//     the lower case name with "_" replaced by ".", e.g. "APP_LOG_LEVEL" is
+//     "log.level"
+//   - Merge, Sub and Explain see the env values
+//
+// DisableEnv turns off the env lookup entirely. An empty variable is treated as
+// not set in all the modes.
+
+// Inner env mapping of a snapshot, nil is the default raw key fallback.
+type envMapping struct {
+	prefix   string
+	disabled bool
+}
+
+// SetEnvPrefix enables the structured env mapping with the prefix, see above.
+// The prefix is usually ended by "_", e.g. "APP_". With an empty prefix the
+// keys are still mapped, e.g. "db.host" is "DB_HOST", but Keys doesn't list
+// the variables.
+func (c *Config) SetEnvPrefix(prefix string) {
+	c.update(func(s *snapshot) {
+		s.env = &envMapping{prefix: prefix}
+	})
+}
+
+// DisableEnv turns off the env lookup, only the options are used.
+func (c *Config) DisableEnv() {
+	c.update(func(s *snapshot) {
+		s.env = &envMapping{disabled: true}
+	})
+}
+
+// EnvOverrides gets the sorted keys whose values come from the environment
+// variables under the structured mapping, including the keys which are only
+// set by the env. It's empty if SetEnvPrefix is not called.
+func (c *Config) EnvOverrides() []string {
+	s := c.load()
+	keys := []string{}
+	if !s.mapped() {
+		return keys
+	}
+	for _, k := range s.keys() {
+		if _, ok := s.lookupEnv(k); ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Inner method, return true if the structured env mapping is enabled.
+func (s *snapshot) mapped() bool {
+	return s.env != nil && !s.env.disabled
+}
+
+// Inner method, return the name of the environment variable of the key.
+func (s *snapshot) envName(key string) string {
+	if s.mapped() {
+		return envName(s.env.prefix, key)
+	}
+	return key
+}
+
+// Inner method, find the value of the key in the environment variables.
+func (s *snapshot) lookupEnv(key string) (string, bool) {
+	if s.env != nil && s.env.disabled {
+		return "", false
+	}
+	v := os.Getenv(s.envName(key))
+	return v, v != ""
+}
+
+// Inner method, return the keys of the options and the keys mapped from the
+// environment variables with the prefix.
+func (s *snapshot) keys() []string {
+	keys := make([]string, 0, len(s.options))
+	for k := range s.options {
+		keys = append(keys, k)
+	}
+	for k := range s.flags {
+		if _, ok := s.options[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	if !s.mapped() || s.env.prefix == "" {
+		return keys
+	}
+
+	names := make(map[string]string, len(keys))
+	for _, k := range keys {
+		names[envName(s.env.prefix, k)] = k
+	}
+	for _, e := range os.Environ() {
+		i := strings.IndexByte(e, '=')
+		name := e[:i]
+		if !strings.HasPrefix(name, s.env.prefix) || len(name) == len(s.env.prefix) || i == len(e)-1 {
+			continue
+		}
+		if _, ok := names[name]; ok {
+			continue
+		}
+		keys = append(keys, strings.ToLower(strings.Replace(name[len(s.env.prefix):], "_", ".", -1)))
+	}
+	return keys
+}
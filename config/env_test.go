@@ -0,0 +1,109 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestEnvDefaultFallback(t *testing.T) {
+	t.Setenv("CONFIG_TEST_RAW", "raw")
+	t.Setenv("DB_HOST", "db.env")
+
+	conf := New()
+	conf.SetOption("db.host", "db.local")
+	if v := conf.String("CONFIG_TEST_RAW", ""); v != "raw" {
+		t.Errorf("Expected raw key env fallback, but was %v", v)
+	}
+	if v := conf.String("db.host", ""); v != "db.local" {
+		t.Errorf("Expected db.host not overridden by default, but was %v", v)
+	}
+	if v := conf.EnvOverrides(); len(v) != 0 {
+		t.Errorf("Expected no env overrides by default, but was %v", v)
+	}
+
+	conf.DisableEnv()
+	if v := conf.String("CONFIG_TEST_RAW", "none"); v != "none" {
+		t.Errorf("Expected env disabled, but was %v", v)
+	}
+	if v := conf.Explain("CONFIG_TEST_RAW"); v != "CONFIG_TEST_RAW is not set" {
+		t.Errorf("Expected not set when env disabled, but was %v", v)
+	}
+}
+
+func TestEnvPrefix(t *testing.T) {
+	t.Setenv("CONFIG_TEST_DB_HOST", "db.env")
+	t.Setenv("CONFIG_TEST_DB_MAX_CONNS", "20")
+	t.Setenv("CONFIG_TEST_LOG_LEVEL", "debug")
+	t.Setenv("CONFIG_TEST_EMPTY", "")
+
+	conf := New()
+	conf.SetOption("db.host", "db.local")
+	conf.SetOption("db.port", "3306")
+	conf.SetOption("db.max_conns", "10")
+	conf.SetEnvPrefix("CONFIG_TEST_")
+
+	cases := map[string]string{
+		"db.host":      "db.env",
+		"db.port":      "3306",
+		"db.max_conns": "20",
+		"log.level":    "debug",
+	}
+	for k, expected := range cases {
+		if v := conf.String(k, ""); v != expected {
+			t.Errorf("Expected %s to be %s, but was %v", k, expected, v)
+		}
+	}
+
+	keys := conf.Keys()
+	sort.Strings(keys)
+	if expected := []string{"db.host", "db.max_conns", "db.port", "log.level"}; !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected keys %v, but was %v", expected, keys)
+	}
+	if v, expected := conf.EnvOverrides(), []string{"db.host", "db.max_conns", "log.level"}; !reflect.DeepEqual(v, expected) {
+		t.Errorf("Expected env overrides %v, but was %v", expected, v)
+	}
+	if v := conf.Explain("db.host"); v != "db.host = db.env (env CONFIG_TEST_DB_HOST)" {
+		t.Errorf("Expected explain env, but was %v", v)
+	}
+
+	sub := conf.Sub("db")
+	if v := sub.String("host", ""); v != "db.env" {
+		t.Errorf("Expected sub host db.env, but was %v", v)
+	}
+}
+
+func TestEnvMerge(t *testing.T) {
+	t.Setenv("CONFIG_TEST_DB_HOST", "db.env")
+	t.Setenv("CONFIG_TEST_DB_USER", "root")
+
+	base := New()
+	base.SetOption("db.host", "db.base")
+	base.SetOption("db.port", "3306")
+
+	// The env of the target is merged.
+	target := New()
+	target.SetOption("db.port", "3307")
+	target.SetEnvPrefix("CONFIG_TEST_")
+	base.Merge(target)
+	if v := base.String("db.host", ""); v != "db.base" {
+		t.Errorf("Expected db.host kept, but was %v", v)
+	}
+	if v := base.String("db.user", ""); v != "root" {
+		t.Errorf("Expected db.user merged from env, but was %v", v)
+	}
+
+	// The env of current config counts as existing.
+	conf := New()
+	conf.SetEnvPrefix("CONFIG_TEST_")
+	other := New()
+	other.SetOption("db.host", "db.other")
+	conf.Merge(other)
+	if v := conf.String("db.host", ""); v != "db.env" {
+		t.Errorf("Expected db.host from env, but was %v", v)
+	}
+}
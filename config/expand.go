@@ -0,0 +1,134 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"github.com/roverli/utils/errors"
+	"os"
+	"strings"
+)
+
+// Interpolation syntax in the option values:
+//
+//	${key}            the value of another key
+//	${key:-fallback}  the value of another key, or fallback if it does not exist
+//	${ENV:NAME}       the environment variable NAME
+//	${ENV:NAME:-fb}   the environment variable NAME, or fb if it is empty
+//	$${               a literal "${"
+//
+// The references are expanded lazily when the value is read, the fallback
+// may contain references too.
+
+// Inner method, find the value of the key and expand the references in it.
+// The second return value is false if the key does not exist.
+func (s *snapshot) get(key string) (string, bool, errors.Error) {
+	v, ok, _, err := s.getSecret(key)
+	return v, ok, err
+}
+
+// Inner method, like get, the third return value is true if the value is
+// a secret or references a secret.
+func (s *snapshot) getSecret(key string) (string, bool, bool, errors.Error) {
+	v, ok, secret, err := s.fetch(key)
+	if !ok || secret || err != nil {
+		return v, ok, secret, err
+	}
+	v, secret, err = s.expand(v, []string{key})
+	return v, true, secret, err
+}
+
+// Inner method, expand the references in text, the second return value is
+// true if a secret is referenced.
+// The stack holds the keys being expanded, for the cycle detection.
+func (s *snapshot) expand(text string, stack []string) (string, bool, errors.Error) {
+	if !strings.Contains(text, "${") {
+		return text, false, nil
+	}
+
+	secret := false
+
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		if strings.HasPrefix(text[i:], "$${") {
+			b.WriteString("${")
+			i += 3
+			continue
+		}
+		if !strings.HasPrefix(text[i:], "${") {
+			b.WriteByte(text[i])
+			i++
+			continue
+		}
+
+		end := closingBrace(text, i+2)
+		if end < 0 {
+			return "", secret, errors.Newf("config: key %s: unterminated reference in %q%s",
+				stack[len(stack)-1], text, s.where(stack[len(stack)-1]))
+		}
+		v, sv, err := s.resolve(text[i+2:end], stack)
+		if err != nil {
+			return "", secret, err
+		}
+		secret = secret || sv
+		b.WriteString(v)
+		i = end + 1
+	}
+	return b.String(), secret, nil
+}
+
+// Inner method, resolve the reference expression between "${" and "}".
+func (s *snapshot) resolve(ref string, stack []string) (string, bool, errors.Error) {
+	name, fallback, hasFallback := ref, "", false
+	if i := strings.Index(ref, ":-"); i >= 0 {
+		name, fallback, hasFallback = ref[:i], ref[i+2:], true
+	}
+	name = strings.TrimSpace(name)
+
+	if strings.HasPrefix(name, "ENV:") {
+		if v := os.Getenv(name[len("ENV:"):]); v != "" {
+			return v, false, nil
+		}
+	} else {
+		for i, k := range stack {
+			if k == name {
+				chain := append(append([]string{}, stack[i:]...), name)
+				return "", false, errors.Newf("config: key %s: reference cycle %s%s",
+					stack[0], strings.Join(chain, " -> "), s.where(stack[0]))
+			}
+		}
+		v, ok, secret, err := s.fetch(name)
+		if err != nil || secret {
+			return v, secret, err
+		}
+		if ok {
+			return s.expand(v, append(stack, name))
+		}
+	}
+
+	if hasFallback {
+		return s.expand(fallback, stack)
+	}
+	return "", false, errors.Newf("config: key %s: undefined reference ${%s}%s",
+		stack[len(stack)-1], name, s.where(stack[len(stack)-1]))
+}
+
+// Inner method, return the index of the "}" closing the reference starting
+// at i, nested references are skipped. Return -1 if it's not closed.
+func closingBrace(s string, i int) int {
+	depth := 1
+	for ; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "${"):
+			depth++
+			i++
+		case s[i] == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,70 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	t.Setenv("CONFIG_TEST_HOME", "/home/tom")
+
+	conf := New()
+	conf.SetOption("app.home", "/opt/app")
+	conf.SetOption("log.dir", "${app.home}/logs")
+	conf.SetOption("log.file", "${log.dir}/app.log")
+	conf.SetOption("user.home", "${ENV:CONFIG_TEST_HOME}")
+	conf.SetOption("tmp.dir", "${ENV:CONFIG_TEST_NOT_EXIST:-/tmp}")
+	conf.SetOption("data.dir", "${data.root:-${app.home}/data}")
+	conf.SetOption("literal", "$${app.home}")
+	conf.SetOption("workers", "${cpu:-4}")
+
+	cases := map[string]string{
+		"log.dir":   "/opt/app/logs",
+		"log.file":  "/opt/app/logs/app.log",
+		"user.home": "/home/tom",
+		"tmp.dir":   "/tmp",
+		"data.dir":  "/opt/app/data",
+		"literal":   "${app.home}",
+	}
+	for k, expected := range cases {
+		v, err := conf.StringE(k, "")
+		if err != nil {
+			t.Errorf("Expected %s expand no error, but was %v", k, err.Message())
+		}
+		if v != expected {
+			t.Errorf("Expected %s to be %q, but was %q", k, expected, v)
+		}
+	}
+
+	if v := conf.Int("workers", -1); v != 4 {
+		t.Errorf("Expected workers to be 4, but was %v", v)
+	}
+}
+
+func TestExpandError(t *testing.T) {
+	conf := New()
+	conf.SetOption("a", "${b}")
+	conf.SetOption("b", "x${c}")
+	conf.SetOption("c", "${a}")
+	conf.SetOption("undefined", "${nothing}")
+	conf.SetOption("unterminated", "${a")
+
+	_, err := conf.StringE("a", "")
+	if err == nil || !strings.Contains(err.Message(), "a -> b -> c -> a") {
+		t.Errorf("Expected reference cycle error, but was %v", err)
+	}
+	if v := conf.String("a", "default"); v != "default" {
+		t.Errorf(`Expected a to fall back to "default", but was %q`, v)
+	}
+
+	if _, err = conf.StringE("undefined", ""); err == nil || !strings.Contains(err.Message(), "${nothing}") {
+		t.Errorf("Expected undefined reference error, but was %v", err)
+	}
+	if _, err = conf.StringE("unterminated", ""); err == nil {
+		t.Error("Expected unterminated reference error.")
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"flag"
+	"sort"
+	"strings"
+)
+
+// BindFlags registers a flag for each key of the config and of the schemas,
+// the flag name is the key, e.g. "-db.host". The default value shown by
+// fs.PrintDefaults is the current value of the key in conf, or the default
+// value of the schema field, the secrets are redacted. The usage text is the
+// Doc of the schema field. The keys already defined in fs and the keys which
+// are not valid flag names, e.g. "a=b" or "-a", are skipped.
+//
+// When fs is parsed, the flags set on the command line are written into conf
+// as the highest precedence layer: they override the options, the env and
+// the later SetOption calls, and they are not written back by WriteTo.
+// A value is checked against the schema field, e.g.
+//
+//	fs := flag.NewFlagSet("app", flag.ExitOnError)
+//	config.BindFlags(fs, conf, schema)
+//	fs.Parse(os.Args[1:]) // "-db.port=3307" overrides db.port of conf
+func BindFlags(fs *flag.FlagSet, conf *Config, schemas ...*Schema) {
+	fields := make(map[string]*Field)
+	for _, schema := range schemas {
+		for _, f := range schema.fields {
+			if _, ok := fields[f.key]; !ok {
+				fields[f.key] = f
+			}
+		}
+	}
+
+	keys := conf.Keys()
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if fs.Lookup(k) != nil || !isFlagName(k) {
+			continue
+		}
+		v := &flagValue{conf: conf, key: k, field: fields[k]}
+		usage := ""
+		if v.field != nil {
+			usage = v.field.doc
+		}
+		fs.Var(v, k, usage)
+	}
+}
+
+// Inner method, whether the key can be registered as a flag name, the flag
+// package panics on an empty name, a name starting with "-" or containing "=".
+func isFlagName(key string) bool {
+	return key != "" && !strings.HasPrefix(key, "-") && !strings.Contains(key, "=")
+}
+
+// Inner flag.Value of a config key, Set writes the flag value into the config.
+type flagValue struct {
+	conf  *Config
+	key   string
+	field *Field
+}
+
+// String returns the current value of the key, it's the default value when
+// the flag is registered.
+func (v *flagValue) String() string {
+	// The flag package calls String on a zero value.
+	if v.conf == nil {
+		return ""
+	}
+
+	s := v.conf.load()
+	value, ok, secret, err := s.getSecret(v.key)
+	switch {
+	case secret:
+		return redacted
+	case err != nil:
+		value, _ = s.lookup(v.key)
+	case !ok && v.field != nil:
+		value = v.field.defaultv
+	}
+	return value
+}
+
+func (v *flagValue) Set(value string) error {
+	if v.field != nil {
+		if err := v.field.check(value); err != nil {
+			return err
+		}
+	}
+	v.conf.setFlag(v.key, value)
+	return nil
+}
+
+// IsBoolFlag makes "-key" mean "-key=true" for a bool field of the schema.
+func (v *flagValue) IsBoolFlag() bool {
+	return v.field != nil && v.field.typ == TypeBool
+}
+
+// Inner method, set the flag value of the key.
+func (c *Config) setFlag(key string, value string) {
+	c.update(func(s *snapshot) {
+		flags := make(map[string]string, len(s.flags)+1)
+		for k, v := range s.flags {
+			flags[k] = v
+		}
+		flags[key] = value
+		s.flags = flags
+	})
+}
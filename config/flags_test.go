@@ -0,0 +1,117 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestBindFlags(t *testing.T) {
+	t.Setenv("CONFIG_TEST_DB_HOST", "db.env")
+	conf := New()
+	conf.SetOption("db.host", "localhost")
+	conf.SetOption("db.port", "3306")
+	conf.SetOption("db.password", "ENC(abc)")
+	conf.SetEnvPrefix("CONFIG_TEST_")
+
+	schema := NewSchema()
+	schema.Key("db.port", TypeInt).Range(1, 65535).Doc("The database port.")
+	schema.Key("debug", TypeBool).Default("false").Doc("Enable the debug mode.")
+
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	fs.SetOutput(&bytes.Buffer{})
+	fs.String("name", "app", "already defined")
+	BindFlags(fs, conf, schema)
+
+	if f := fs.Lookup("db.port"); f == nil || f.DefValue != "3306" || f.Usage != "The database port." {
+		t.Fatalf("Expected flag db.port with default and usage, but was %+v", f)
+	}
+	if f := fs.Lookup("db.host"); f == nil || f.DefValue != "db.env" {
+		t.Errorf("Expected the env value as default, but was %+v", f)
+	}
+	if f := fs.Lookup("db.password"); f == nil || f.DefValue != redacted {
+		t.Errorf("Expected the secret redacted, but was %+v", f)
+	}
+	if f := fs.Lookup("debug"); f == nil || f.DefValue != "false" {
+		t.Errorf("Expected the schema default, but was %+v", f)
+	}
+
+	err := fs.Parse([]string{"-db.host", "db.flag", "-db.port=3307", "-debug", "arg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := conf.String("db.host", ""); v != "db.flag" {
+		t.Errorf("Expected the flag to override the env, but was %s", v)
+	}
+	if v := conf.Int("db.port", 0); v != 3307 {
+		t.Errorf("Expected port 3307, but was %d", v)
+	}
+	if !conf.Bool("debug", false) {
+		t.Errorf("Expected the bool flag set")
+	}
+	conf.SetOption("db.port", "1")
+	if v := conf.Int("db.port", 0); v != 3307 {
+		t.Errorf("Expected the flag to override SetOption, but was %d", v)
+	}
+
+	if o, _ := conf.Origin("db.host"); o.String() != "flag -db.host" {
+		t.Errorf("Expected flag origin, but was %s", o)
+	}
+	if e := conf.Explain("db.port"); e != "db.port = 3307 (flag -db.port)" {
+		t.Errorf("Expected flag explain, but was %s", e)
+	}
+	if v := conf.Sub("db").String("port", ""); v != "3307" {
+		t.Errorf("Expected the flag in the sub config, but was %s", v)
+	}
+
+	// The flags are not written back to the file.
+	var b strings.Builder
+	conf.WriteTo(&b)
+	if strings.Contains(b.String(), "3307") {
+		t.Errorf("Expected the flags not written, but was %q", b.String())
+	}
+}
+
+func TestBindFlagsInvalid(t *testing.T) {
+	conf := New()
+	schema := NewSchema()
+	schema.Key("db.port", TypeInt).Range(1, 65535)
+
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	var out bytes.Buffer
+	fs.SetOutput(&out)
+	BindFlags(fs, conf, schema)
+
+	if err := fs.Parse([]string{"-db.port=70000"}); err == nil {
+		t.Errorf("Expected the invalid flag error")
+	}
+	if !strings.Contains(out.String(), "70000 is greater than 65535") {
+		t.Errorf("Expected the range error, but was %q", out.String())
+	}
+	if _, ok := conf.Origin("db.port"); ok {
+		t.Errorf("Expected the invalid value not set")
+	}
+}
+
+func TestBindFlagsInvalidName(t *testing.T) {
+	conf := New()
+	conf.SetOption("a=b", "x")
+	conf.SetOption("-c", "x")
+	conf.SetOption("", "x")
+	conf.SetOption("name", "app")
+
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	fs.SetOutput(&bytes.Buffer{})
+	BindFlags(fs, conf)
+
+	n := 0
+	fs.VisitAll(func(*flag.Flag) { n++ })
+	if n != 1 || fs.Lookup("name") == nil {
+		t.Errorf("Expected only flag name, but was %d flags", n)
+	}
+}
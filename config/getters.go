@@ -0,0 +1,370 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"github.com/roverli/utils/errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Every typed getter has two forms: X returns default value if the key does not
+// exist or the value is malformed, XE returns default value and nil if the key
+// does not exist, and returns the error if the value is malformed.
+
+// Inner method, find the value of the key and convert it by parse.
+// It does nothing if the key does not exist.
+func (c *Config) convert(key string, typ string, parse func(v string) error) errors.Error {
+	c.touch(key)
+	s := c.load()
+	v, ok, secret, err := s.getSecret(key)
+	if !ok || err != nil {
+		return err
+	}
+	if e := parse(v); e != nil {
+		// The inner error may contain the value, so it's not wrapped for a secret.
+		if secret {
+			return errors.Newf("config: key %s: cann't convert %s to %s%s", key, redacted, typ, s.where(key))
+		}
+		return errors.Wrapf(e, "config: key %s: cann't convert %q to %s%s", key, v, typ, s.where(key))
+	}
+	return nil
+}
+
+// Int64 gets the int64 value for the given key in the configuration.
+// The literal syntax is the same as Int.
+// It returns default value if the key does not exist or cann't be converted to int64.
+func (c *Config) Int64(key string, defaultv int64) int64 {
+	v, _ := c.Int64E(key, defaultv)
+	return v
+}
+
+// Int64E is like Int64, but returns the error if the value cann't be converted to int64.
+func (c *Config) Int64E(key string, defaultv int64) (int64, errors.Error) {
+	n := defaultv
+	err := c.convert(key, "int64", func(v string) (e error) {
+		n, e = parseInt(v, 64)
+		return
+	})
+	if err != nil {
+		return defaultv, err
+	}
+	return n, nil
+}
+
+// Uint64 gets the uint64 value for the given key in the configuration.
+// The literal syntax is the same as Int.
+// It returns default value if the key does not exist or cann't be converted to uint64.
+func (c *Config) Uint64(key string, defaultv uint64) uint64 {
+	v, _ := c.Uint64E(key, defaultv)
+	return v
+}
+
+// Uint64E is like Uint64, but returns the error if the value cann't be converted to uint64.
+func (c *Config) Uint64E(key string, defaultv uint64) (uint64, errors.Error) {
+	n := defaultv
+	err := c.convert(key, "uint64", func(v string) (e error) {
+		n, e = parseUint(v, 64)
+		return
+	})
+	if err != nil {
+		return defaultv, err
+	}
+	return n, nil
+}
+
+// Duration gets the time.Duration value for the given key in the configuration.
+// The value is in time.ParseDuration format, e.g. "1m30s", "500ms".
+// It returns default value if the key does not exist or cann't be converted to duration.
+func (c *Config) Duration(key string, defaultv time.Duration) time.Duration {
+	v, _ := c.DurationE(key, defaultv)
+	return v
+}
+
+// DurationE is like Duration, but returns the error if the value cann't be converted to duration.
+func (c *Config) DurationE(key string, defaultv time.Duration) (time.Duration, errors.Error) {
+	d := defaultv
+	err := c.convert(key, "duration", func(v string) (e error) {
+		d, e = time.ParseDuration(v)
+		return
+	})
+	if err != nil {
+		return defaultv, err
+	}
+	return d, nil
+}
+
+// Bytes gets the byte size for the given key in the configuration.
+// The value is a number with an optional unit, e.g. "512MB", "1.5GiB", "1024".
+// The units are case insensitive, B, KB, MB, GB, TB, PB are powers of 1000,
+// KiB, MiB, GiB, TiB, PiB are powers of 1024.
+// It returns default value if the key does not exist or cann't be converted to size.
+func (c *Config) Bytes(key string, defaultv int64) int64 {
+	v, _ := c.BytesE(key, defaultv)
+	return v
+}
+
+// BytesE is like Bytes, but returns the error if the value cann't be converted to size.
+func (c *Config) BytesE(key string, defaultv int64) (int64, errors.Error) {
+	n := defaultv
+	err := c.convert(key, "byte size", func(v string) (e error) {
+		n, e = parseBytes(v)
+		return
+	})
+	if err != nil {
+		return defaultv, err
+	}
+	return n, nil
+}
+
+// Time gets the time.Time value for the given key in the configuration.
+// The value is in RFC3339 format, e.g. "2006-01-02T15:04:05Z07:00", the date
+// and time may be separated by a space as in TOML, e.g. "2006-01-02 15:04:05Z".
+// It returns default value if the key does not exist or cann't be converted to time.
+func (c *Config) Time(key string, defaultv time.Time) time.Time {
+	v, _ := c.TimeE(key, defaultv)
+	return v
+}
+
+// TimeE is like Time, but returns the error if the value cann't be converted to time.
+func (c *Config) TimeE(key string, defaultv time.Time) (time.Time, errors.Error) {
+	t := defaultv
+	err := c.convert(key, "time", func(v string) (e error) {
+		t, e = parseTime(v)
+		return
+	})
+	if err != nil {
+		return defaultv, err
+	}
+	return t, nil
+}
+
+// StringSlice gets the comma separated list for the given key in the configuration.
+// The items are trimmed, an item can be quoted by '"' or "'" to contain
+// commas or spaces, '\' escapes the next char in a double quoted item.
+// e.g. `a, "b, c", 'd'` is ["a", "b, c", "d"]. An empty value is an empty list.
+// It returns default value if the key does not exist or cann't be converted to list.
+func (c *Config) StringSlice(key string, defaultv []string) []string {
+	v, _ := c.StringSliceE(key, defaultv)
+	return v
+}
+
+// StringSliceE is like StringSlice, but returns the error if the value cann't be converted to list.
+func (c *Config) StringSliceE(key string, defaultv []string) ([]string, errors.Error) {
+	s := defaultv
+	err := c.convert(key, "list", func(v string) (e error) {
+		s, e = splitList(v)
+		return
+	})
+	if err != nil {
+		return defaultv, err
+	}
+	return s, nil
+}
+
+// StringMap gets the comma separated "key:value" pairs for the given key in the configuration.
+// The pairs are split as StringSlice, e.g. `a:1, b:2` is {"a": "1", "b": "2"}.
+// It returns default value if the key does not exist or cann't be converted to map.
+func (c *Config) StringMap(key string, defaultv map[string]string) map[string]string {
+	v, _ := c.StringMapE(key, defaultv)
+	return v
+}
+
+// StringMapE is like StringMap, but returns the error if the value cann't be converted to map.
+func (c *Config) StringMapE(key string, defaultv map[string]string) (map[string]string, errors.Error) {
+	m := defaultv
+	err := c.convert(key, "map", func(v string) error {
+		items, e := splitList(v)
+		if e != nil {
+			return e
+		}
+		m = make(map[string]string, len(items))
+		for _, item := range items {
+			i := strings.Index(item, ":")
+			if i < 0 {
+				return fmt.Errorf("missing ':' in %q", item)
+			}
+			m[strings.TrimSpace(item[:i])] = strings.TrimSpace(item[i+1:])
+		}
+		return nil
+	})
+	if err != nil {
+		return defaultv, err
+	}
+	return m, nil
+}
+
+// Inner method, parse the RFC3339 time, the date and time may be separated by
+// a space or a lower case "t".
+func parseTime(s string) (time.Time, error) {
+	if len(s) > 10 && (s[10] == ' ' || s[10] == 't') {
+		s = s[:10] + "T" + s[11:]
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// Inner method, parse the signed integer literal.
+// Decimal literals never have the octal meaning of a leading "0".
+func parseInt(s string, bitSize int) (int64, error) {
+	if hasBasePrefix(strings.TrimLeft(s, "+-")) {
+		return strconv.ParseInt(s, 0, bitSize)
+	}
+	if !validUnderscores(s) {
+		return 0, strconv.ErrSyntax
+	}
+	return strconv.ParseInt(strings.Replace(s, "_", "", -1), 10, bitSize)
+}
+
+// Inner method, parse the unsigned integer literal.
+func parseUint(s string, bitSize int) (uint64, error) {
+	if hasBasePrefix(s) {
+		return strconv.ParseUint(s, 0, bitSize)
+	}
+	if !validUnderscores(s) {
+		return 0, strconv.ErrSyntax
+	}
+	return strconv.ParseUint(strings.Replace(s, "_", "", -1), 10, bitSize)
+}
+
+func hasBasePrefix(s string) bool {
+	if len(s) < 2 || s[0] != '0' {
+		return false
+	}
+	switch s[1] {
+	case 'x', 'X', 'o', 'O', 'b', 'B':
+		return true
+	}
+	return false
+}
+
+// Inner method, "_" is only allowed between digits.
+func validUnderscores(s string) bool {
+	s = strings.TrimLeft(s, "+-")
+	return !strings.HasPrefix(s, "_") && !strings.HasSuffix(s, "_") && !strings.Contains(s, "__")
+}
+
+var byteUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1e3,
+	"kb":  1e3,
+	"m":   1e6,
+	"mb":  1e6,
+	"g":   1e9,
+	"gb":  1e9,
+	"t":   1e12,
+	"tb":  1e12,
+	"p":   1e15,
+	"pb":  1e15,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+	"pib": 1 << 50,
+}
+
+// Inner method, parse the byte size such as "512MB", "1.5GiB".
+func parseBytes(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '_'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+
+	unit, ok := byteUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", strings.TrimSpace(s[i:]))
+	}
+
+	num := s[:i]
+	if !strings.Contains(num, ".") {
+		n, err := parseInt(num, 64)
+		if err != nil {
+			return 0, err
+		}
+		if n > math.MaxInt64/int64(unit) {
+			return 0, strconv.ErrRange
+		}
+		return n * int64(unit), nil
+	}
+
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, err
+	}
+	if f*unit >= math.MaxInt64 {
+		return 0, strconv.ErrRange
+	}
+	return int64(f * unit), nil
+}
+
+// Inner method, split the comma separated list with quoted items.
+func splitList(s string) ([]string, error) {
+	items := []string{}
+	if strings.TrimSpace(s) == "" {
+		return items, nil
+	}
+
+	var item strings.Builder
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch == ',':
+			if quoted {
+				items = append(items, item.String())
+			} else {
+				items = append(items, strings.TrimSpace(item.String()))
+			}
+			item.Reset()
+			quoted = false
+		case (ch == '"' || ch == '\'') && strings.TrimSpace(item.String()) == "" && !quoted:
+			end, text, err := unquote(s, i)
+			if err != nil {
+				return nil, err
+			}
+			item.Reset()
+			item.WriteString(text)
+			quoted = true
+			i = end
+		case quoted:
+			if ch != ' ' && ch != '\t' {
+				return nil, fmt.Errorf("unexpected %q after quoted item", ch)
+			}
+		default:
+			item.WriteByte(ch)
+		}
+	}
+	if quoted {
+		items = append(items, item.String())
+	} else {
+		items = append(items, strings.TrimSpace(item.String()))
+	}
+	return items, nil
+}
+
+// Inner method, read the quoted text starting at s[start].
+// Return the index of the closing quote and the unquoted text.
+func unquote(s string, start int) (int, string, error) {
+	q := s[start]
+	var b strings.Builder
+	for i := start + 1; i < len(s); i++ {
+		switch {
+		case s[i] == q:
+			return i, b.String(), nil
+		case s[i] == '\\' && q == '"' && i+1 < len(s):
+			i++
+			b.WriteByte(s[i])
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return 0, "", fmt.Errorf("unterminated quote in %q", s)
+}
@@ -0,0 +1,174 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIntegers(t *testing.T) {
+	conf := New()
+	conf.SetOption("hex", "0xff")
+	conf.SetOption("big", "1_000_000_000_000")
+	conf.SetOption("zero", "010")
+	conf.SetOption("neg", "-0b101")
+	conf.SetOption("bad", "1__0")
+
+	if v := conf.Int("hex", -1); v != 255 {
+		t.Errorf("Expected hex to be 255, but was %v", v)
+	}
+	if v := conf.Int64("big", -1); v != 1000000000000 {
+		t.Errorf("Expected big to be 1000000000000, but was %v", v)
+	}
+	if v := conf.Uint64("zero", 0); v != 10 {
+		t.Errorf("Expected zero to be decimal 10, but was %v", v)
+	}
+	if v := conf.Int64("neg", 0); v != -5 {
+		t.Errorf("Expected neg to be -5, but was %v", v)
+	}
+	if v, err := conf.Uint64E("neg", 7); err == nil || v != 7 {
+		t.Errorf("Expected neg uint64 error and default, but was %v %v", v, err)
+	}
+	if v, err := conf.IntE("bad", 7); err == nil || v != 7 {
+		t.Errorf("Expected bad int error and default, but was %v %v", v, err)
+	}
+	if v, err := conf.Int64E("missing", 7); err != nil || v != 7 {
+		t.Errorf("Expected missing int64 default without error, but was %v %v", v, err)
+	}
+}
+
+func TestDuration(t *testing.T) {
+	conf := New()
+	conf.SetOption("timeout", "1m30s")
+	conf.SetOption("bad", "90")
+
+	if v := conf.Duration("timeout", 0); v != 90*time.Second {
+		t.Errorf("Expected timeout to be 1m30s, but was %v", v)
+	}
+	if v, err := conf.DurationE("bad", time.Second); err == nil || v != time.Second {
+		t.Errorf("Expected bad duration error and default, but was %v %v", v, err)
+	}
+}
+
+func TestBytes(t *testing.T) {
+	conf := New()
+	cases := map[string]int64{
+		"1024":   1024,
+		"512MB":  512 * 1000 * 1000,
+		"1GiB":   1 << 30,
+		"1.5kib": 1536,
+		"2 k":    2000,
+		"10b":    10,
+	}
+	for raw, expected := range cases {
+		conf.SetOption("size", raw)
+		v, err := conf.BytesE("size", -1)
+		if err != nil || v != expected {
+			t.Errorf("Expected %q to be %d, but was %v %v", raw, expected, v, err)
+		}
+	}
+
+	for _, raw := range []string{"12XB", "MB", "-1KB", "99999999PiB"} {
+		conf.SetOption("size", raw)
+		if _, err := conf.BytesE("size", -1); err == nil {
+			t.Errorf("Expected %q to be a bad size.", raw)
+		}
+	}
+}
+
+func TestTime(t *testing.T) {
+	conf := New()
+	conf.SetOption("start", "2014-05-01T08:30:00Z")
+	conf.SetOption("bad", "2014-05-01")
+
+	expected := time.Date(2014, 5, 1, 8, 30, 0, 0, time.UTC)
+	if v := conf.Time("start", time.Time{}); !v.Equal(expected) {
+		t.Errorf("Expected start to be %v, but was %v", expected, v)
+	}
+	if _, err := conf.TimeE("bad", time.Time{}); err == nil {
+		t.Error("Expected bad time error.")
+	}
+}
+
+func TestStringSlice(t *testing.T) {
+	conf := New()
+	cases := map[string][]string{
+		"":                          {},
+		"a":                         {"a"},
+		" a , b ,c":                 {"a", "b", "c"},
+		`a, "b, c", 'd'`:            {"a", "b, c", "d"},
+		`" x ", "say \"hi\"", ,'z'`: {" x ", `say "hi"`, "", "z"},
+	}
+	for raw, expected := range cases {
+		conf.SetOption("list", raw)
+		v, err := conf.StringSliceE("list", nil)
+		if err != nil || !reflect.DeepEqual(v, expected) {
+			t.Errorf("Expected %q to be %q, but was %q %v", raw, expected, v, err)
+		}
+	}
+
+	for _, raw := range []string{`"a`, `"a" b`} {
+		conf.SetOption("list", raw)
+		if v, err := conf.StringSliceE("list", nil); err == nil || v != nil {
+			t.Errorf("Expected %q to be a bad list, but was %q", raw, v)
+		}
+	}
+}
+
+func TestStringMap(t *testing.T) {
+	conf := New()
+	conf.SetOption("map", `a:1, b : 2, "c:x,y"`)
+	conf.SetOption("bad", "a:1,b")
+
+	expected := map[string]string{"a": "1", "b": "2", "c": "x,y"}
+	if v := conf.StringMap("map", nil); !reflect.DeepEqual(v, expected) {
+		t.Errorf("Expected map to be %v, but was %v", expected, v)
+	}
+	if v, err := conf.StringMapE("bad", nil); err == nil || v != nil {
+		t.Errorf("Expected bad map error and default, but was %v %v", v, err)
+	}
+}
+
+func TestBoolLiterals(t *testing.T) {
+	conf := New()
+	cases := map[string]bool{
+		"y": true, "ON": true, "true": true, "True": true, "1": true,
+		"n": false, "off": false, "false": false, "FALSE": false, "0": false,
+	}
+	for literal, expected := range cases {
+		conf.SetOption("b", literal)
+		if v, err := conf.BoolE("b", !expected); err != nil || v != expected {
+			t.Errorf("Expected %q to be %v, but was %v %v", literal, expected, v, err)
+		}
+	}
+	for _, literal := range []string{"yes", "no", "t", ""} {
+		conf.SetOption("b", literal)
+		if _, err := conf.BoolE("b", false); err == nil {
+			t.Errorf("Expected %q to be rejected", literal)
+		}
+	}
+}
+
+func TestTypedE(t *testing.T) {
+	conf := New()
+	conf.SetOption("age", "2o")
+	conf.SetOption("man", "yes")
+	conf.SetOption("height", "tall")
+
+	if _, err := conf.IntE("age", 0); err == nil {
+		t.Error("Expected age int error.")
+	}
+	if _, err := conf.BoolE("man", false); err == nil {
+		t.Error("Expected man bool error.")
+	}
+	if _, err := conf.FloatE("height", 0); err == nil {
+		t.Error("Expected height float error.")
+	}
+	if v := conf.Int("age", 3); v != 3 {
+		t.Errorf("Expected age to fall back to 3, but was %v", v)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInclude(t *testing.T) {
+	conf, err := Load("testdata/include/main.conf")
+	if err != nil {
+		t.Fatalf("Expected load main.conf no error, but was %v", err.Message())
+	}
+
+	cases := map[string]string{
+		"name":          "main",
+		"level":         "warn",
+		"db.host":       "db.local",
+		"db.port":       "3307",
+		"database.user": "root",
+	}
+	for k, expected := range cases {
+		if v := conf.String(k, ""); v != expected {
+			t.Errorf("Expected %s to be %q, but was %q", k, expected, v)
+		}
+	}
+	if len(conf.Keys()) != len(cases) {
+		t.Errorf("Expected %d keys, but was %v", len(cases), conf.Keys())
+	}
+
+	if v := conf.Explain("db.port"); !strings.Contains(v, "20-prod.conf:2") {
+		t.Errorf("Expected db.port defined in 20-prod.conf, but was %s", v)
+	}
+}
+
+func TestIncludeWriteTo(t *testing.T) {
+	conf, err := Load("testdata/include/main.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	conf.WriteTo(&buf)
+	content, _ := os.ReadFile("testdata/include/main.conf")
+	if buf.String() != string(content) {
+		t.Errorf("Expected included options not written, but was:\n%s", buf.String())
+	}
+
+	conf.SetOption("level", "error")
+	buf.Reset()
+	conf.WriteTo(&buf)
+	if !strings.Contains(buf.String(), "include conf.d/*.conf\nlevel = error\n") {
+		t.Errorf("Expected modified included option written as new option, but was:\n%s", buf.String())
+	}
+}
+
+func TestIncludeCycle(t *testing.T) {
+	_, err := Load("testdata/include/cycle/a.conf")
+	expected := "config: include cycle testdata/include/cycle/a.conf:2 -> " +
+		"testdata/include/cycle/b.conf:2 -> testdata/include/cycle/a.conf"
+	if err == nil || err.Message() != expected {
+		t.Errorf("Expected include cycle error %q, but was %v", expected, err)
+	}
+}
+
+func TestIncludeError(t *testing.T) {
+	dir := t.TempDir()
+	main, sub := filepath.Join(dir, "main.conf"), filepath.Join(dir, "sub.conf")
+	os.WriteFile(main, []byte("include sub.conf\n"), 0644)
+	os.WriteFile(sub, []byte("a = 1\nbad\n"), 0644)
+
+	_, err := Load(main)
+	expected := "parse error: bad (" + sub + ":2), included from " + main + ":1"
+	if err == nil || err.Message() != expected {
+		t.Errorf("Expected include chain error %q, but was %v", expected, err)
+	}
+
+	os.WriteFile(main, []byte("include missing.conf\n"), 0644)
+	if _, err = Load(main); err == nil {
+		t.Error("Expected missing include file error.")
+	}
+
+	os.WriteFile(main, []byte("include empty.d/*.conf\ninclude = 1\n"), 0644)
+	conf, err := Load(main)
+	if err != nil || conf.String("include", "") != "1" {
+		t.Errorf("Expected empty glob and include key allowed, but was %v", err)
+	}
+}
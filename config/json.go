@@ -0,0 +1,224 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/roverli/utils/errors"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LoadJSON reads options from the JSON file.
+//
+// The document is flattened into dotted keys: the nested objects are joined by
+// ".", the array elements are indexed by "[i]", e.g.
+//
+//	{"db": {"host": "a", "slaves": ["b", "c"]}}
+//
+// is "db.host = a", "db.slaves[0] = b" and "db.slaves[1] = c".
+// The numbers keep their literal text, the bools are "true" and "false",
+// the nulls are ignored.
+func LoadJSON(fname string) (*Config, errors.Error) {
+	content, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cann't open file %s", fname)
+	}
+	return migrated(parseJSON(content, fname))
+}
+
+// Inner method, parse the JSON content, name is used in the error messages.
+func parseJSON(content []byte, name string) (*Config, errors.Error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, errors.Wrapf(err, "parse error: %s", name)
+	}
+	if _, ok := doc.(map[string]interface{}); !ok {
+		return nil, errors.Newf("parse error: %s: the document must be an object", name)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.Newf("parse error: %s: unexpected data after the document", name)
+	}
+
+	options := make(map[string]string)
+	flatten("", doc, options)
+	return newConfig(&snapshot{options: options, positions: make(map[string]position)}), nil
+}
+
+// Inner method, flatten the decoded document into options.
+func flatten(key string, v interface{}, options map[string]string) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if key != "" {
+				k = key + "." + k
+			}
+			flatten(k, child, options)
+		}
+	case []interface{}:
+		for i, child := range v {
+			flatten(fmt.Sprintf("%s[%d]", key, i), child, options)
+		}
+	case nil:
+	case string:
+		options[key] = v
+	default:
+		options[key] = fmt.Sprint(v)
+	}
+}
+
+// WriteJSON writes the options to w as a nested JSON document, it's the
+// inverse of LoadJSON. The values which are JSON numbers or bools are written
+// as numbers and bools, others are written as strings. The keys are sorted.
+// It returns error if the keys conflict, e.g. both "a" and "a.b" are set, or
+// an array index is not less than the number of keys.
+func (c *Config) WriteJSON(w io.Writer) errors.Error {
+	doc, err := unflatten(c.load().options)
+	if err != nil {
+		return err
+	}
+
+	content, e := json.MarshalIndent(doc, "", "  ")
+	if e != nil {
+		return errors.Wrap(e, "write json error.")
+	}
+	if _, e = w.Write(append(content, '\n')); e != nil {
+		return errors.Wrap(e, "write json error.")
+	}
+	return nil
+}
+
+// Inner method, rebuild the nested document from the flat options.
+func unflatten(options map[string]string) (map[string]interface{}, errors.Error) {
+	keys := make([]string, 0, len(options))
+	for k := range options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	root := map[string]interface{}{}
+	for _, k := range keys {
+		path, ok := splitPath(k)
+		if !ok {
+			return nil, errors.Newf("config: key %s cann't be converted to json path", k)
+		}
+		// An array index beyond the number of keys would only add nulls.
+		for _, p := range path {
+			if i, ok := p.(int); ok && i >= len(keys) {
+				return nil, errors.Newf("config: key %s has array index %d out of range", k, i)
+			}
+		}
+		if !setPath(root, path, jsonValue(options[k])) {
+			return nil, errors.Newf("config: key %s conflicts with other keys", k)
+		}
+	}
+	return root, nil
+}
+
+// Inner method, split the key into the object names (string) and the array
+// indexes (int), e.g. "a.b[1].c" is ["a", "b", 1, "c"].
+func splitPath(key string) ([]interface{}, bool) {
+	path := []interface{}{}
+	for _, part := range strings.Split(key, ".") {
+		name := part
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			name = part[:i]
+		}
+		if name == "" {
+			return nil, false
+		}
+		path = append(path, name)
+
+		for rest := part[len(name):]; rest != ""; {
+			end := strings.IndexByte(rest, ']')
+			if rest[0] != '[' || end < 0 {
+				return nil, false
+			}
+			i, err := strconv.Atoi(rest[1:end])
+			if err != nil || i < 0 {
+				return nil, false
+			}
+			path = append(path, i)
+			rest = rest[end+1:]
+		}
+	}
+	return path, true
+}
+
+// Inner method, set the value at the path of the node, the missing objects and
+// arrays are created. Return false if the path conflicts with the existing nodes.
+func setPath(node interface{}, path []interface{}, value interface{}) bool {
+	var child interface{}
+	switch p := path[0].(type) {
+	case string:
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if len(path) == 1 {
+			if _, exists := m[p]; exists {
+				return false
+			}
+			m[p] = value
+			return true
+		}
+		if child, ok = m[p]; !ok {
+			child = newNode(path[1])
+			m[p] = child
+		}
+		return setPath(child, path[1:], value)
+	case int:
+		a, ok := node.(*[]interface{})
+		if !ok {
+			return false
+		}
+		for len(*a) <= p {
+			*a = append(*a, nil)
+		}
+		if len(path) == 1 {
+			if (*a)[p] != nil {
+				return false
+			}
+			(*a)[p] = value
+			return true
+		}
+		if (*a)[p] == nil {
+			(*a)[p] = newNode(path[1])
+		}
+		return setPath((*a)[p], path[1:], value)
+	}
+	return false
+}
+
+// Inner method, create an object or array node for the next path element.
+// The arrays are held by pointers so that they can grow in place.
+func newNode(next interface{}) interface{} {
+	if _, ok := next.(int); ok {
+		return &[]interface{}{}
+	}
+	return map[string]interface{}{}
+}
+
+// Inner method, convert the option value to the JSON value.
+func jsonValue(v string) interface{} {
+	switch v {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if json.Valid([]byte(v)) && v != "" && (v[0] == '-' || (v[0] >= '0' && v[0] <= '9')) {
+		return json.Number(v)
+	}
+	return v
+}
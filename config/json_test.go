@@ -0,0 +1,158 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadJSON(t *testing.T) {
+	conf, err := LoadJSON("testdata/app.json")
+	if err != nil {
+		t.Fatalf("Expected load app.json no error, but was %v", err.Message())
+	}
+
+	cases := map[string]string{
+		"name":            "app",
+		"debug":           "true",
+		"db.host":         "db.local",
+		"db.port":         "3306",
+		"db.ratio":        "0.75",
+		"db.slaves[0]":    "s1",
+		"db.slaves[1]":    "s2",
+		"servers[0].host": "a",
+		"servers[0].port": "8001",
+		"servers[1].host": "b",
+		"servers[1].port": "8002",
+	}
+	for k, expected := range cases {
+		if v, ok := conf.load().lookup(k); !ok || v != expected {
+			t.Errorf("Expected %q to be %q, but was %q", k, expected, v)
+		}
+	}
+	if len(conf.Keys()) != len(cases) {
+		t.Errorf("Expected %d keys, but was %q", len(cases), conf.Keys())
+	}
+
+	if !conf.Bool("debug", false) {
+		t.Errorf("Expected debug to be true")
+	}
+	if v := conf.Int("db.port", 0); v != 3306 {
+		t.Errorf("Expected db.port to be 3306, but was %v", v)
+	}
+}
+
+func TestParseJSONError(t *testing.T) {
+	if _, err := parseJSON([]byte(`{"a": `), "bad.json"); err == nil {
+		t.Errorf("Expected malformed json error, but was nil")
+	}
+	if _, err := parseJSON([]byte(`[1, 2]`), "array.json"); err == nil || !strings.Contains(err.Message(), "object") {
+		t.Errorf("Expected root must be object error, but was %v", err)
+	}
+	for _, content := range []string{`{"a": 1} trailing`, `{"a": 1}{"b": 2}`} {
+		if _, err := parseJSON([]byte(content), "trailing.json"); err == nil || !strings.Contains(err.Message(), "after the document") {
+			t.Errorf("Expected trailing data error of %s, but was %v", content, err)
+		}
+	}
+	if _, err := parseJSON([]byte("{\"a\": 1}\n\n"), "space.json"); err != nil {
+		t.Errorf("Expected trailing spaces ignored, but was %v", err)
+	}
+}
+
+func TestWriteJSONRoundTrip(t *testing.T) {
+	conf, err := LoadJSON("testdata/app.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := conf.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Expected valid json, but was %v:\n%s", err, buf.String())
+	}
+	expected := map[string]interface{}{
+		"name":  "app",
+		"debug": true,
+		"db": map[string]interface{}{
+			"host":   "db.local",
+			"port":   float64(3306),
+			"ratio":  0.75,
+			"slaves": []interface{}{"s1", "s2"},
+		},
+		"servers": []interface{}{
+			map[string]interface{}{"host": "a", "port": float64(8001)},
+			map[string]interface{}{"host": "b", "port": float64(8002)},
+		},
+	}
+	if !reflect.DeepEqual(doc, expected) {
+		t.Errorf("Expected %v, but was %v", expected, doc)
+	}
+
+	again, err := parseJSON(buf.Bytes(), "buffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again.load().options, conf.load().options) {
+		t.Errorf("Expected %v to round trip, but was %v", conf.load().options, again.load().options)
+	}
+}
+
+func TestWriteJSONConflict(t *testing.T) {
+	conf := New()
+	conf.SetOption("a", "1")
+	conf.SetOption("a.b", "2")
+	if err := conf.WriteJSON(&bytes.Buffer{}); err == nil || !strings.Contains(err.Message(), "a.b") {
+		t.Errorf("Expected key a.b conflicts error, but was %v", err)
+	}
+
+	conf = New()
+	conf.SetOption("a[x]", "1")
+	if err := conf.WriteJSON(&bytes.Buffer{}); err == nil {
+		t.Errorf("Expected malformed index error, but was nil")
+	}
+
+	conf = New()
+	conf.SetOption("a[3000000000]", "1")
+	if err := conf.WriteJSON(&bytes.Buffer{}); err == nil || !strings.Contains(err.Message(), "index 3000000000 out of range") {
+		t.Errorf("Expected index out of range error, but was %v", err)
+	}
+
+	// A sparse array is padded with nulls.
+	conf = New()
+	conf.SetOption("a[1]", "x")
+	conf.SetOption("b", "y")
+	var b bytes.Buffer
+	if err := conf.WriteJSON(&b); err != nil || !strings.Contains(b.String(), "null") {
+		t.Errorf("Expected sparse array, but was %v %s", err, b.String())
+	}
+}
+
+func TestLoadAny(t *testing.T) {
+	cases := map[string]string{
+		"testdata/app.json":       "db.host",
+		"testdata/app.toml":       "db.host",
+		"testdata/app.properties": "db.host",
+		"testdata/app.env":        "DB_HOST",
+		"testdata/section.conf":   "database.host",
+	}
+	for fname, key := range cases {
+		conf, err := LoadAny(fname)
+		if err != nil {
+			t.Errorf("Expected load %s no error, but was %v", fname, err.Message())
+			continue
+		}
+		if _, ok := conf.load().options[key]; !ok {
+			t.Errorf("Expected %s to contain %s, but was %q", fname, key, conf.Keys())
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"github.com/roverli/utils/errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A list is written as the indexed keys, or as the repeated "key[] = value"
+// lines which are indexed in order:
+//
+//	servers[] = 10.0.0.1       # "servers[0]"
+//	servers[] = 10.0.0.2       # "servers[1]"
+//
+//	servers.0 = 10.0.0.1
+//	servers.1 = 10.0.0.2
+//
+// The "key[]" lines of the included files are appended to the list. The
+// "key[]" lines of an active profile replace the list of the base options.
+// The arrays of LoadJSON and LoadTOML are indexed the same way.
+
+// List gets the elements of the list for the given key in the configuration,
+// in the index order. The "${...}" references in the elements are expanded.
+// If there is no element but the key has a value, the value is split as
+// StringSlice. It returns default value if the key does not exist or an
+// element cann't be expanded.
+func (c *Config) List(key string, defaultv []string) []string {
+	v, _ := c.ListE(key, defaultv)
+	return v
+}
+
+// ListE is like List, but returns the error if an element cann't be expanded.
+func (c *Config) ListE(key string, defaultv []string) ([]string, errors.Error) {
+	elements := c.load().elements(key)
+	if len(elements) == 0 {
+		return c.StringSliceE(key, defaultv)
+	}
+
+	c.touch(key)
+	list := make([]string, 0, len(elements))
+	for _, k := range elements {
+		v, err := c.StringE(k, "")
+		if err != nil {
+			return defaultv, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+// Inner method, return the keys of the elements of the list in the index order.
+func (s *snapshot) elements(list string) []string {
+	type element struct {
+		key   string
+		index int
+	}
+	elements := []element{}
+	for _, k := range s.keys() {
+		if i, ok := elementIndex(k, list); ok {
+			elements = append(elements, element{k, i})
+		}
+	}
+	sort.Slice(elements, func(i, j int) bool {
+		if elements[i].index != elements[j].index {
+			return elements[i].index < elements[j].index
+		}
+		return elements[i].key < elements[j].key
+	})
+
+	keys := make([]string, len(elements))
+	for i, e := range elements {
+		keys[i] = e.key
+	}
+	return keys
+}
+
+// Children gets the sorted names of the groups nested in the key, e.g.
+// "a" and "b" for the keys "pools.a.size", "pools.a.idle" and "pools.b.size".
+// The keys of a group are read by Sub, e.g. Sub("pools.a"). A plain key such as
+// "pools.max" is not a group. The numeric names, e.g. of "servers.0.host",
+// are sorted by number.
+func (c *Config) Children(key string) []string {
+	prefix := key + "."
+	seen := make(map[string]bool)
+	names := []string{}
+	for _, k := range c.load().keys() {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		rest := k[len(prefix):]
+		i := strings.Index(rest, ".")
+		if i <= 0 || seen[rest[:i]] {
+			continue
+		}
+		seen[rest[:i]] = true
+		names = append(names, rest[:i])
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, errA := strconv.Atoi(names[i])
+		b, errB := strconv.Atoi(names[j])
+		if errA == nil && errB == nil {
+			return a < b
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// Inner method, return the index of the key if it's an element of the list,
+// i.e. "list[i]" or "list.i".
+func elementIndex(key string, list string) (int, bool) {
+	if !strings.HasPrefix(key, list) || len(key) <= len(list)+1 {
+		return 0, false
+	}
+	rest := key[len(list):]
+	switch {
+	case rest[0] == '[' && rest[len(rest)-1] == ']':
+		rest = rest[1 : len(rest)-1]
+	case rest[0] == '.':
+		rest = rest[1:]
+	default:
+		return 0, false
+	}
+	i, err := strconv.Atoi(rest)
+	if err != nil || i < 0 || strings.HasPrefix(rest, "+") {
+		return 0, false
+	}
+	return i, true
+}
+
+// Inner method, return the next free "list[i]" key of the profile for a
+// "list[] = value" line.
+func (p *parser) element(profile string, list string) string {
+	lists, ok := p.lists[profile]
+	if !ok {
+		lists = make(map[string]bool)
+		p.lists[profile] = lists
+	}
+	lists[list] = true
+
+	s := p.profile(profile)
+	for i := 0; ; i++ {
+		k := fmt.Sprintf("%s[%d]", list, i)
+		if _, ok := s.options[k]; !ok {
+			return k
+		}
+	}
+}
+
+// Inner method, remove the elements of the list from the options.
+func removeList(s *snapshot, list string) {
+	for k := range s.options {
+		if _, ok := elementIndex(k, list); ok {
+			delete(s.options, k)
+			delete(s.positions, k)
+		}
+	}
+}
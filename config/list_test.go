@@ -0,0 +1,126 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestList(t *testing.T) {
+	conf, err := LoadProfile("testdata/list/main.conf", "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"servers":     "[10.0.0.1 10.0.0.3 10.0.0.4]",
+		"ports":       "[80 443 8080]",
+		"hosts":       "[a b]",
+		"pools.nodes": "[n1 n2]",
+		"missing":     "[x]",
+	}
+	for k, expected := range cases {
+		if v := conf.List(k, []string{"x"}); fmt.Sprint(v) != expected {
+			t.Errorf("Expected list %s to be %s, but was %v", k, expected, v)
+		}
+	}
+	if v := conf.String("servers[1]", ""); v != "10.0.0.3" {
+		t.Errorf("Expected indexed key servers[1], but was %s", v)
+	}
+
+	conf.SetOption("servers[3]", "${missing}")
+	if _, err := conf.ListE("servers", nil); err == nil {
+		t.Errorf("Expected expand error of the element")
+	}
+}
+
+func TestListJSON(t *testing.T) {
+	conf, err := LoadJSON("testdata/app.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := conf.List("db.slaves", nil); len(v) != 2 {
+		t.Errorf("Expected the json array as list, but was %v", v)
+	}
+}
+
+func TestListProfile(t *testing.T) {
+	conf, err := LoadProfile("testdata/list/main.conf", "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := conf.List("pools.nodes", nil); fmt.Sprint(v) != "[p1]" {
+		t.Errorf("Expected the profile list to replace the base list, but was %v", v)
+	}
+
+	// The replaced base list is kept when written.
+	var b strings.Builder
+	conf.WriteTo(&b)
+	if s := b.String(); !strings.Contains(s, "nodes[] = n1\nnodes[] = n2\n") || !strings.Contains(s, "pools.nodes[] = p1") {
+		t.Errorf("Expected the lists written, but was %q", s)
+	}
+
+	conf.SetOption("pools.nodes[0]", "p2")
+	b.Reset()
+	conf.WriteTo(&b)
+	if s := b.String(); !strings.Contains(s, "pools.nodes[] = p2") {
+		t.Errorf("Expected the element written in place, but was %q", s)
+	}
+}
+
+func TestChildren(t *testing.T) {
+	conf, err := Load("testdata/list/main.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := conf.Children("pools"); fmt.Sprint(v) != "[batch default]" {
+		t.Errorf("Expected children [batch default], but was %v", v)
+	}
+	if v := conf.Sub("pools.batch").Int("size", 0); v != 4 {
+		t.Errorf("Expected size 4, but was %d", v)
+	}
+
+	conf.SetOption("servers.10.host", "a")
+	conf.SetOption("servers.2.host", "b")
+	if v := conf.Children("servers"); fmt.Sprint(v) != "[2 10]" {
+		t.Errorf("Expected numeric children sorted by number, but was %v", v)
+	}
+	if v := conf.Children("missing"); len(v) != 0 {
+		t.Errorf("Expected no children, but was %v", v)
+	}
+}
+
+func TestElementIndex(t *testing.T) {
+	cases := map[string]int{
+		"a[0]":    0,
+		"a.12":    12,
+		"a[-1]":   -1,
+		"a.+1":    -1,
+		"a.0.b":   -1,
+		"a[0].b":  -1,
+		"ab[0]":   -1,
+		"a":       -1,
+		"a[]":     -1,
+		"a.x":     -1,
+		"a[1][2]": -1,
+	}
+	for k, expected := range cases {
+		i, ok := elementIndex(k, "a")
+		if !ok {
+			i = -1
+		}
+		if i != expected {
+			t.Errorf("Expected index of %s to be %d, but was %d", k, expected, i)
+		}
+	}
+}
+
+func TestParseListError(t *testing.T) {
+	if _, err := LoadReader(strings.NewReader("[] = a\n"), "bad.conf"); err == nil {
+		t.Errorf("Expected parse error of an empty list key")
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadReader(t *testing.T) {
+	conf, err := LoadReader(strings.NewReader("name = app\n[db]\nhost = db.local\n"), "<stdin>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := conf.String("db.host", ""); v != "db.local" {
+		t.Errorf("Expected db.host to be db.local, but was %v", v)
+	}
+	if v := conf.Explain("db.host"); v != "db.host = db.local (<stdin>:3)" {
+		t.Errorf("Expected position in <stdin>, but was %v", v)
+	}
+
+	var buf bytes.Buffer
+	if _, err := conf.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "name = app\n[db]\nhost = db.local\n" {
+		t.Errorf("Expected layout preserved, but was %q", buf.String())
+	}
+
+	_, err = LoadReader(strings.NewReader("a = 1\nbad line\n"), "<stdin>")
+	if err == nil || !strings.Contains(err.Message(), "(<stdin>:2)") {
+		t.Errorf("Expected parse error at <stdin>:2, but was %v", err)
+	}
+}
+
+func TestLoadReaderInclude(t *testing.T) {
+	content, err := os.ReadFile("testdata/include/main.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromReader, e := LoadReader(bytes.NewReader(content), "testdata/include/main.conf")
+	if e != nil {
+		t.Fatal(e)
+	}
+	fromFile, e := Load("testdata/include/main.conf")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if d := Diff(fromFile, fromReader); !d.IsEmpty() {
+		t.Errorf("Expected the same options as Load, but was:\n%s", d)
+	}
+}
+
+func TestLoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app/main.conf":            {Data: []byte("name = app\ninclude conf.d/*.conf\ninclude /shared/base.conf\n")},
+		"app/conf.d/10-db.conf":    {Data: []byte("[db]\nhost = db.local\n")},
+		"app/conf.d/20-cache.conf": {Data: []byte("[cache]\naddr = 127.0.0.1\n")},
+		"shared/base.conf":         {Data: []byte("timeout = 3s\n")},
+		"cycle/a.conf":             {Data: []byte("include b.conf\n")},
+		"cycle/b.conf":             {Data: []byte("include a.conf\n")},
+	}
+
+	conf, err := LoadFS(fsys, "app/main.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{
+		"name":       "app",
+		"db.host":    "db.local",
+		"cache.addr": "127.0.0.1",
+		"timeout":    "3s",
+	}
+	for k, expected := range cases {
+		if v := conf.String(k, ""); v != expected {
+			t.Errorf("Expected %s to be %s, but was %v", k, expected, v)
+		}
+	}
+	if v := conf.Explain("db.host"); v != "db.host = db.local (app/conf.d/10-db.conf:2)" {
+		t.Errorf("Expected position in the fs, but was %v", v)
+	}
+
+	if _, err := LoadFS(fsys, "cycle/a.conf"); err == nil || !strings.Contains(err.Message(), "include cycle") {
+		t.Errorf("Expected include cycle error, but was %v", err)
+	}
+	if _, err := LoadFS(fsys, "missing.conf"); err == nil || !strings.Contains(err.Message(), "missing.conf") {
+		t.Errorf("Expected open error, but was %v", err)
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"github.com/roverli/utils/errors"
+	"strings"
+	"sync"
+)
+
+// Migrations evolve the key namespace without breaking the old deployments,
+// e.g. when "db_host" is renamed to "db.host":
+//
+//	config.Rename("db_host", "db.host")
+//	config.Transform("timeout", func(v string) (string, error) {
+//		if _, err := strconv.Atoi(v); err == nil {
+//			return v + "s", nil // the seconds are a duration now
+//		}
+//		return v, nil
+//	})
+//	config.Remove("legacy.mode")
+//
+// The rules registered by the package functions are applied by Load and the
+// other LoadX functions after the file is parsed, in the registration order,
+// so "a -> b" followed by "b -> c" migrates "a" to "c". Each applied rule
+// produces a Warning, which is passed to the OnWarning callbacks and kept in
+// Config.Warnings. In the strict mode, the deprecated keys fail the load.
+//
+// The rules match the keys loaded from the files and set on the config, the
+// env variables are not migrated.
+type Migrations struct {
+	mu        sync.Mutex
+	rules     []rule
+	strict    bool
+	onWarning []func(w Warning)
+}
+
+// Inner representation of a migration rule.
+type rule struct {
+	key string
+
+	// The new key of a rename rule.
+	to string
+
+	// The function of a transform rule.
+	transform func(value string) (string, error)
+
+	// The key is removed.
+	remove bool
+}
+
+// Warning describes a migration rule applied to a config.
+type Warning struct {
+	// The deprecated key and where it was defined.
+	Key    string
+	Origin Origin
+
+	// The new key of a renamed key, empty for the others.
+	NewKey string
+
+	// The description, e.g.
+	// "deprecated key db_host (app.conf:3), renamed to db.host".
+	Message string
+}
+
+func (w Warning) String() string {
+	return w.Message
+}
+
+// NewMigrations creates an empty migration registry. Use it to migrate the
+// configs explicitly by Apply, the package functions use a default one.
+func NewMigrations() *Migrations {
+	return &Migrations{}
+}
+
+// Rename renames the key old to new. If new is set too, old is ignored.
+func (m *Migrations) Rename(old, new string) *Migrations {
+	return m.add(rule{key: old, to: new})
+}
+
+// Transform replaces the raw value of the key by the result of f. It's applied
+// only if the value is changed, an error of f fails the migration.
+func (m *Migrations) Transform(key string, f func(value string) (string, error)) *Migrations {
+	return m.add(rule{key: key, transform: f})
+}
+
+// Remove removes the key.
+func (m *Migrations) Remove(key string) *Migrations {
+	return m.add(rule{key: key, remove: true})
+}
+
+// Strict sets the strict mode, a rule which applies to the config becomes an
+// error and the config is not modified.
+func (m *Migrations) Strict(strict bool) *Migrations {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.strict = strict
+	return m
+}
+
+// OnWarning registers a callback invoked for each applied rule.
+func (m *Migrations) OnWarning(f func(w Warning)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onWarning = append(m.onWarning, f)
+}
+
+// Inner method, register the rule.
+func (m *Migrations) add(r rule) *Migrations {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rules = append(m.rules, r)
+	return m
+}
+
+// Apply applies the rules to the config in order, and returns the warnings of
+// the applied rules. In the strict mode, the applied rules are reported as an
+// error and the config is not modified. The config is not modified either if
+// a transform fails.
+func (m *Migrations) Apply(c *Config) ([]Warning, errors.Error) {
+	m.mu.Lock()
+	rules := append([]rule{}, m.rules...)
+	strict := m.strict
+	callbacks := append([]func(w Warning){}, m.onWarning...)
+	m.mu.Unlock()
+
+	if len(rules) == 0 {
+		return []Warning{}, nil
+	}
+
+	warnings := []Warning{}
+	problems := []string{}
+	c.update(func(s *snapshot) {
+		old := c.load()
+		for _, r := range rules {
+			w, err := c.migrate(s, r)
+			if err != nil {
+				problems = append(problems, err.Error())
+			} else if w != nil {
+				warnings = append(warnings, *w)
+			}
+		}
+		if strict {
+			for _, w := range warnings {
+				problems = append(problems, w.Message)
+			}
+		}
+		if len(problems) > 0 {
+			*s = *old
+			return
+		}
+		c.warnings = append(c.warnings, warnings...)
+	})
+
+	if len(problems) > 0 {
+		return nil, errors.Newf("config: migrate failed:\n\t%s", strings.Join(problems, "\n\t"))
+	}
+	for _, w := range warnings {
+		for _, f := range callbacks {
+			f(w)
+		}
+	}
+	return warnings, nil
+}
+
+// Inner method, apply the rule to the snapshot of c. Return nil if the rule
+// doesn't apply.
+func (c *Config) migrate(s *snapshot, r rule) (*Warning, error) {
+	v, ok := s.options[r.key]
+	if !ok {
+		return nil, nil
+	}
+
+	w := &Warning{Key: r.key, NewKey: r.to}
+	w.Origin, _ = c.origin(s, r.key)
+	at := ""
+	if o := w.Origin.String(); o != "" {
+		at = " (" + o + ")"
+	}
+
+	switch {
+	case r.remove:
+		w.Message = fmt.Sprintf("deprecated key %s%s, removed", r.key, at)
+	case r.transform != nil:
+		nv, err := r.transform(v)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: cann't transform value: %v%s", r.key, err, at)
+		}
+		if nv == v {
+			return nil, nil
+		}
+		if _, _, secret, _ := s.getSecret(r.key); secret {
+			v, nv = redacted, redacted
+		}
+		w.Message = fmt.Sprintf("deprecated value of key %s%s, %s is transformed to %s", r.key, at, v, nv)
+		s.options[r.key] = nv
+		return w, nil
+	default:
+		if _, exists := s.options[r.to]; exists {
+			w.Message = fmt.Sprintf("deprecated key %s%s, ignored since %s is set", r.key, at, r.to)
+		} else {
+			w.Message = fmt.Sprintf("deprecated key %s%s, renamed to %s", r.key, at, r.to)
+			s.options[r.to] = v
+			if p, ok := s.positions[r.key]; ok {
+				s.positions[r.to] = p
+			}
+		}
+	}
+	delete(s.options, r.key)
+	delete(s.positions, r.key)
+	return w, nil
+}
+
+// Warnings gets the warnings of the migrations applied to the config.
+func (c *Config) Warnings() []Warning {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Warning{}, c.warnings...)
+}
+
+// The default migrations applied by the LoadX functions.
+var migrations = NewMigrations()
+
+// Rename registers a rename rule in the default migrations, see Migrations.
+func Rename(old, new string) {
+	migrations.Rename(old, new)
+}
+
+// Transform registers a transform rule in the default migrations, see Migrations.
+func Transform(key string, f func(value string) (string, error)) {
+	migrations.Transform(key, f)
+}
+
+// Remove registers a remove rule in the default migrations, see Migrations.
+func Remove(key string) {
+	migrations.Remove(key)
+}
+
+// StrictMigrations sets the strict mode of the default migrations, the
+// LoadX functions fail if the loaded file has a deprecated key.
+func StrictMigrations(strict bool) {
+	migrations.Strict(strict)
+}
+
+// OnWarning registers a callback of the default migrations, it's invoked for
+// each applied rule when a file is loaded.
+func OnWarning(f func(w Warning)) {
+	migrations.OnWarning(f)
+}
+
+// Inner method, apply the default migrations to the loaded config.
+func migrated(c *Config, err errors.Error) (*Config, errors.Error) {
+	if err != nil {
+		return nil, err
+	}
+	if _, err := migrations.Apply(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
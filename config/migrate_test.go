@@ -0,0 +1,169 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func seconds(v string) (string, error) {
+	if _, err := strconv.Atoi(v); err == nil {
+		return v + "s", nil
+	}
+	return v, nil
+}
+
+func TestMigrationsApply(t *testing.T) {
+	conf, err := Load("testdata/migrate.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := []string{}
+	m := NewMigrations().
+		Rename("db_host", "db.host").
+		Rename("db.host", "database.host").
+		Transform("timeout", seconds).
+		Transform("db.port", seconds).
+		Transform("missing", seconds).
+		Remove("legacy.mode")
+	m.OnWarning(func(w Warning) {
+		events = append(events, w.Key)
+	})
+
+	warnings, err := m.Apply(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"deprecated key db_host (testdata/migrate.conf:1), renamed to db.host",
+		"deprecated key db.host (testdata/migrate.conf:1), renamed to database.host",
+		"deprecated value of key timeout (testdata/migrate.conf:2), 30 is transformed to 30s",
+		"deprecated value of key db.port (testdata/migrate.conf:5), 3306 is transformed to 3306s",
+		"deprecated key legacy.mode (testdata/migrate.conf:8), removed",
+	}
+	if fmt.Sprint(warnings) != fmt.Sprint(expected) {
+		t.Errorf("Expected warnings %v, but was %v", expected, warnings)
+	}
+	if fmt.Sprint(events) != "[db_host db.host timeout db.port legacy.mode]" {
+		t.Errorf("Expected the events of the applied rules, but was %v", events)
+	}
+	if len(conf.Warnings()) != len(expected) {
+		t.Errorf("Expected the warnings kept in config, but was %v", conf.Warnings())
+	}
+
+	if v := conf.String("database.host", ""); v != "db.old" {
+		t.Errorf("Expected renamed value db.old, but was %s", v)
+	}
+	if v := conf.Duration("timeout", 0); v.String() != "30s" {
+		t.Errorf("Expected transformed timeout 30s, but was %v", v)
+	}
+	for _, k := range []string{"db_host", "db.host", "legacy.mode"} {
+		if _, ok := conf.Origin(k); ok {
+			t.Errorf("Expected %s to be removed", k)
+		}
+	}
+	if o, _ := conf.Origin("database.host"); o.String() != "testdata/migrate.conf:1" {
+		t.Errorf("Expected renamed key keeps the position, but was %s", o)
+	}
+
+	// Applied again, nothing to migrate.
+	if warnings, err := m.Apply(conf); err != nil || len(warnings) != 0 {
+		t.Errorf("Expected no warning, but was %v %v", warnings, err)
+	}
+}
+
+func TestMigrationsRenameExisting(t *testing.T) {
+	conf := New()
+	conf.SetOption("db_host", "old")
+	conf.SetOption("db.host", "new")
+
+	warnings, err := NewMigrations().Rename("db_host", "db.host").Apply(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || warnings[0].Message != "deprecated key db_host (set), ignored since db.host is set" {
+		t.Errorf("Expected ignored warning, but was %v", warnings)
+	}
+	if v := conf.String("db.host", ""); v != "new" || conf.String("db_host", "") != "" {
+		t.Errorf("Expected new value kept and old key removed, but was %s", v)
+	}
+}
+
+func TestMigrationsStrict(t *testing.T) {
+	conf, _ := Load("testdata/migrate.conf")
+	m := NewMigrations().Rename("db_host", "db.host").Remove("legacy.mode").Strict(true)
+
+	_, err := m.Apply(conf)
+	if err == nil {
+		t.Fatal("Expected strict migration error")
+	}
+	expected := "config: migrate failed:\n" +
+		"\tdeprecated key db_host (testdata/migrate.conf:1), renamed to db.host\n" +
+		"\tdeprecated key legacy.mode (testdata/migrate.conf:8), removed"
+	if err.Message() != expected {
+		t.Errorf("Expected %q, but was %q", expected, err.Message())
+	}
+	if conf.String("db_host", "") != "db.old" || conf.String("db.host", "") != "" || len(conf.Warnings()) != 0 {
+		t.Errorf("Expected config not modified")
+	}
+}
+
+func TestMigrationsTransformError(t *testing.T) {
+	conf, _ := Load("testdata/migrate.conf")
+	m := NewMigrations().Remove("legacy.mode").Transform("timeout", func(v string) (string, error) {
+		return "", fmt.Errorf("bad timeout")
+	})
+	if _, err := m.Apply(conf); err == nil || !strings.Contains(err.Message(), "key timeout: cann't transform value: bad timeout (testdata/migrate.conf:2)") {
+		t.Errorf("Expected transform error, but was %v", err)
+	}
+	if conf.String("legacy.mode", "") != "on" {
+		t.Errorf("Expected config not modified")
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	defer func() { migrations = NewMigrations() }()
+
+	Rename("db_host", "db.host")
+	Transform("timeout", seconds)
+	Remove("legacy.mode")
+	warnings := []Warning{}
+	OnWarning(func(w Warning) {
+		warnings = append(warnings, w)
+	})
+
+	conf, err := Load("testdata/migrate.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 3 || warnings[0].NewKey != "db.host" || warnings[0].Origin.Line != 1 {
+		t.Errorf("Expected 3 warnings, but was %v", warnings)
+	}
+	if conf.String("db.host", "") != "db.old" || conf.String("timeout", "") != "30s" {
+		t.Errorf("Expected migrated config, but was %v", conf.Keys())
+	}
+	if len(conf.Snapshot().Warnings()) != 3 {
+		t.Errorf("Expected the snapshot to keep the warnings")
+	}
+
+	// The migrated file is written with the new key.
+	var b strings.Builder
+	conf.WriteTo(&b)
+	if s := b.String(); strings.Contains(s, "db_host") || !strings.Contains(s, "host = db.old") {
+		t.Errorf("Expected the new key written, but was %q", s)
+	}
+
+	StrictMigrations(true)
+	if _, err := Load("testdata/migrate.conf"); err == nil {
+		t.Errorf("Expected strict load error")
+	}
+	if _, err := LoadJSON("testdata/app.json"); err != nil {
+		t.Errorf("Expected no deprecated key in json, but was %v", err)
+	}
+}
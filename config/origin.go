@@ -0,0 +1,211 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The kinds of Origin.
+const (
+	OriginFile   = "file"   // loaded from File at Line
+	OriginEnv    = "env"    // the environment variable Name
+	OriginMerge  = "merge"  // merged from another config, File and Line if known
+	OriginSet    = "set"    // set by SetOption or the like
+	OriginLayer  = "layer"  // the layer Name of a Layered config, File and Line if known
+	OriginSecret = "secret" // read from the secret file Name, see Secret
+	OriginFlag   = "flag"   // the command line flag Name, see BindFlags
+)
+
+// Origin describes where the value of a key comes from.
+type Origin struct {
+	Kind string
+	File string
+	Line int
+	Name string
+}
+
+// String describes the origin, e.g. "app.conf:3", "env APP_DB_HOST",
+// "flag -db.host", "merge from app.conf:3" or "set".
+func (o Origin) String() string {
+	at := ""
+	if o.File != "" {
+		at = fmt.Sprintf("%s:%d", o.File, o.Line)
+	}
+	switch o.Kind {
+	case OriginFile:
+		return at
+	case OriginEnv, OriginSecret, OriginFlag:
+		return o.Kind + " " + o.Name
+	case OriginMerge:
+		if at != "" {
+			return "merge from " + at
+		}
+	case OriginLayer:
+		if at != "" {
+			return o.Name + " at " + at
+		}
+		return o.Name
+	}
+	return o.Kind
+}
+
+// Origin gets where the value of the key comes from.
+// The second return value is false if the key does not exist.
+func (c *Config) Origin(key string) (Origin, bool) {
+	return c.origin(c.load(), key)
+}
+
+// Inner method, return the origin of the key in the snapshot of c.
+func (c *Config) origin(s *snapshot, key string) (Origin, bool) {
+	if _, ok := s.flags[key]; ok {
+		return Origin{Kind: OriginFlag, Name: "-" + key}, true
+	}
+	if _, ok := s.lookupEnv(key); ok {
+		if _, inOptions := s.options[key]; s.mapped() || !inOptions {
+			return Origin{Kind: OriginEnv, Name: s.envName(key)}, true
+		}
+	}
+
+	if _, ok := s.options[key]; !ok {
+		if fname, ok := s.lookup(key + secretSuffix); ok {
+			return Origin{Kind: OriginSecret, Name: fname}, true
+		}
+		return Origin{}, false
+	}
+
+	p, hasPosition := s.positions[key]
+	switch {
+	case len(c.layers) > 0:
+		o := Origin{Kind: OriginLayer, File: p.file, Line: p.line}
+		if _, i := resolveLayers(c.layers, key); i >= 0 {
+			o.Name = c.layers[i].Name()
+		}
+		return o, true
+	case hasPosition && p.merged:
+		return Origin{Kind: OriginMerge, File: p.file, Line: p.line}, true
+	case hasPosition:
+		return Origin{Kind: OriginFile, File: p.file, Line: p.line}, true
+	}
+	return Origin{Kind: OriginSet}, true
+}
+
+// Inner method, record the key is read by a getter. The key is usually
+// recorded already, so it's checked first to keep the reads free of writes.
+func (c *Config) touch(key string) {
+	if c.prefix != "" {
+		key = c.prefix + key
+	}
+	if _, ok := c.accessed.Load(key); !ok {
+		c.accessed.Store(key, true)
+	}
+}
+
+// UnusedKey is a key of the config which is never read, see UnusedKeys.
+type UnusedKey struct {
+	Key    string
+	Origin Origin
+
+	// The read key which is probably the correct spelling of Key, or "".
+	Suggestion string
+}
+
+// String describes the unused key, e.g.
+// "db.max_conn (app.conf:7), did you mean db.max_conns?".
+func (u UnusedKey) String() string {
+	s := fmt.Sprintf("%s (%s)", u.Key, u.Origin)
+	if u.Suggestion != "" {
+		s += ", did you mean " + u.Suggestion + "?"
+	}
+	return s
+}
+
+// UnusedKeys gets the sorted keys which are never read by the getters, Bind
+// and Unmarshal of the config, its snapshots or its Sub configs. Call it after
+// the startup code has read the config.
+//
+// A key referenced by a read value, e.g. "b" in "a = ${b}", and the "key_FILE"
+// of a read secret are used. The suggestions are the read keys within a small
+// edit distance, the read keys include the missing keys whose default values
+// were used, so a misspelled key in the file is reported with the right one.
+func (c *Config) UnusedKeys() []UnusedKey {
+	s := c.load()
+	read := []string{}
+	used := make(map[string]bool)
+	c.accessed.Range(func(k, _ interface{}) bool {
+		key := k.(string)
+		if !strings.HasPrefix(key, c.prefix) {
+			return true
+		}
+		key = key[len(c.prefix):]
+		read = append(read, key)
+		s.markUsed(key, used)
+		return true
+	})
+	sort.Strings(read)
+
+	keys := s.keys()
+	sort.Strings(keys)
+	unused := []UnusedKey{}
+	for _, k := range keys {
+		if used[k] {
+			continue
+		}
+		u := UnusedKey{Key: k, Suggestion: suggest(k, read)}
+		u.Origin, _ = c.Origin(k)
+		unused = append(unused, u)
+	}
+	return unused
+}
+
+// Inner method, mark the key, its secret file keys and the keys referenced by
+// its value as used.
+func (s *snapshot) markUsed(key string, used map[string]bool) {
+	if used[key] {
+		return
+	}
+	used[key] = true
+	used[key+secretSuffix] = true
+
+	v, ok := s.lookup(key)
+	if !ok {
+		return
+	}
+	for _, ref := range references(v) {
+		s.markUsed(ref, used)
+	}
+}
+
+// Inner method, return the keys referenced by "${key}" and "${key:-fallback}"
+// in the value, including the references in the fallbacks.
+func references(v string) []string {
+	refs := []string{}
+	for i := 0; i < len(v); i++ {
+		if strings.HasPrefix(v[i:], "$${") {
+			i += 2
+			continue
+		}
+		if !strings.HasPrefix(v[i:], "${") {
+			continue
+		}
+		end := closingBrace(v, i+2)
+		if end < 0 {
+			break
+		}
+		ref := v[i+2 : end]
+		name, fallback := ref, ""
+		if j := strings.Index(ref, ":-"); j >= 0 {
+			name, fallback = ref[:j], ref[j+2:]
+		}
+		if name = strings.TrimSpace(name); !strings.HasPrefix(name, "ENV:") {
+			refs = append(refs, name)
+		}
+		refs = append(refs, references(fallback)...)
+		i = end
+	}
+	return refs
+}
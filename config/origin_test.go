@@ -0,0 +1,115 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"testing"
+)
+
+func TestOrigin(t *testing.T) {
+	t.Setenv("CONFIG_TEST_ORIGIN", "env")
+	conf, err := Load("testdata/unused.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := New()
+	other.SetOption("merged", "1")
+	conf.Merge(other)
+	conf.SetOption("set", "1")
+
+	cases := map[string]string{
+		"db.host":            "testdata/unused.conf:4",
+		"merged":             "merge",
+		"set":                "set",
+		"CONFIG_TEST_ORIGIN": "env CONFIG_TEST_ORIGIN",
+		"db.password":        "secret testdata/unused.conf",
+	}
+	for k, expected := range cases {
+		if o, ok := conf.Origin(k); !ok || o.String() != expected {
+			t.Errorf("Expected origin of %s to be %q, but was %q", k, expected, o)
+		}
+	}
+	if _, ok := conf.Origin("missing"); ok {
+		t.Errorf("Expected no origin of missing key")
+	}
+
+	base, _ := Load("testdata/unused.conf")
+	merged := New()
+	merged.Merge(base)
+	if o, _ := merged.Origin("db.port"); o.String() != "merge from testdata/unused.conf:5" {
+		t.Errorf("Expected merged origin with position, but was %q", o)
+	}
+
+	conf.SetEnvPrefix("CONFIG_TEST_")
+	t.Setenv("CONFIG_TEST_DB_HOST", "db.env")
+	if o, _ := conf.Origin("db.host"); o.Kind != OriginEnv || o.Name != "CONFIG_TEST_DB_HOST" {
+		t.Errorf("Expected env origin, but was %+v", o)
+	}
+
+	layered := Layered(MapSource("defaults", map[string]string{"db.host": "x"}), ConfigSource("file", base))
+	if o, _ := layered.Origin("db.port"); o.String() != "file at testdata/unused.conf:5" {
+		t.Errorf("Expected layer origin, but was %q", o)
+	}
+}
+
+func TestUnusedKeys(t *testing.T) {
+	conf, err := Load("testdata/unused.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Read by a snapshot, a getter, a missing key with default and a secret.
+	conf.Snapshot().String("name", "")
+	conf.String("db.url", "")
+	conf.Int("db.max_conns", 5)
+	conf.String("db.password", "")
+	var target struct {
+		Addr string `conf:"cache.adr"`
+	}
+	conf.Bind(&target)
+
+	unused := conf.UnusedKeys()
+	expected := []string{
+		"cache.addr (testdata/unused.conf:11), did you mean cache.adr?",
+		"db.max_conn (testdata/unused.conf:6), did you mean db.max_conns?",
+	}
+	if len(unused) != len(expected) {
+		t.Fatalf("Expected unused keys %v, but was %v", expected, unused)
+	}
+	for i, u := range unused {
+		if u.String() != expected[i] {
+			t.Errorf("Expected %q, but was %q", expected[i], u)
+		}
+	}
+}
+
+func TestUnusedKeysSub(t *testing.T) {
+	conf, err := Load("testdata/unused.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The keys read by the Sub configs are read from conf.
+	conf.String("name", "")
+	db := conf.Sub("db")
+	db.String("host", "")
+	db.Int("port", 0)
+	db.String("url", "")
+	db.Int("max_conns", 5)
+	db.Snapshot().String("password", "")
+	conf.Sub("cache").String("addr", "")
+
+	unused := conf.UnusedKeys()
+	expected := "db.max_conn (testdata/unused.conf:6), did you mean db.max_conns?"
+	if len(unused) != 1 || unused[0].String() != expected {
+		t.Errorf("Expected unused keys [%s], but was %v", expected, unused)
+	}
+
+	unused = db.UnusedKeys()
+	expected = "max_conn (testdata/unused.conf:6), did you mean max_conns?"
+	if len(unused) != 1 || unused[0].String() != expected {
+		t.Errorf("Expected unused keys [%s], but was %v", expected, unused)
+	}
+}
@@ -0,0 +1,269 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/roverli/utils/errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Inner parser of the config file format, it collects the options of the
+// file and the files included by it.
+type parser struct {
+	options   map[string]string
+	positions map[string]position
+
+	// The file system of the included files, nil is the OS file system.
+	fsys fs.FS
+
+	// The options of the "[profile:name]" blocks and "key@name" lines, by name.
+	profiles map[string]*snapshot
+
+	// The lists of the "key[] = value" lines, by profile name.
+	lists map[string]map[string]bool
+}
+
+// Inner representation of a file being parsed, the chain of frames is
+// used to detect the include cycles and to report the include chain.
+type frame struct {
+	abs  string // the absolute path for the cycle detection
+	name string // the name in the error messages
+	line int    // the line of the include directive in this file
+}
+
+func newParser(fsys fs.FS) *parser {
+	return &parser{
+		options:   make(map[string]string),
+		positions: make(map[string]position),
+		fsys:      fsys,
+		profiles:  make(map[string]*snapshot),
+		lists:     make(map[string]map[string]bool),
+	}
+}
+
+// Inner method, return the absolute name of the file for the cycle detection.
+func (p *parser) abs(fname string) string {
+	if p.fsys != nil {
+		return path.Clean(fname)
+	}
+	if abs, err := filepath.Abs(fname); err == nil {
+		return abs
+	}
+	return fname
+}
+
+// Inner method, open the file in the file system of the parser.
+func (p *parser) open(fname string) (io.ReadCloser, error) {
+	if p.fsys != nil {
+		return p.fsys.Open(fname)
+	}
+	return os.Open(fname)
+}
+
+// Inner method, parse the content of the top file named name.
+func (p *parser) parseReader(r io.Reader, name string) ([]line, errors.Error) {
+	return p.parse(r, []frame{{abs: p.abs(name), name: name}})
+}
+
+// Inner method, parse the file included by the chain.
+func (p *parser) parseFile(fname string, chain []frame) ([]line, errors.Error) {
+	abs := p.abs(fname)
+	for i, f := range chain {
+		if f.abs == abs {
+			sites := []string{}
+			for _, f := range chain[i:] {
+				sites = append(sites, fmt.Sprintf("%s:%d", f.name, f.line))
+			}
+			return nil, errors.Newf("config: include cycle %s -> %s", strings.Join(sites, " -> "), fname)
+		}
+	}
+
+	file, err := p.open(fname)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cann't open file %s%s", fname, includedFrom(chain))
+	}
+	defer file.Close()
+
+	return p.parse(file, append(chain, frame{abs: abs, name: fname}))
+}
+
+// Inner method, parse the content of the last file in the chain.
+func (p *parser) parse(r io.Reader, chain []frame) ([]line, errors.Error) {
+	fname := chain[len(chain)-1].name
+	reader := bufio.NewReader(r)
+	lines := []line{}
+	prefix := ""
+	profile := ""
+	for n := 1; ; n++ {
+
+		raw, err := reader.ReadString('\n')
+
+		if err != nil && err != io.EOF {
+			return nil, errors.Wrapf(err, "read file %s error.", fname)
+		}
+		if err == io.EOF && raw == "" {
+			break
+		}
+
+		raw = strings.TrimRight(raw, "\r\n")
+		l := line{raw: raw}
+		var included []line
+		text := strings.TrimSpace(raw)
+
+		switch {
+		case len(text) == 0 || text[0] == '#':
+		case text[0] == '[':
+			// Section header, the following keys are prefixed by "section.".
+			// The keys after a "[profile:name]" header belong to the profile.
+			name, ok := parseSection(text)
+			if !ok {
+				return nil, parseError(text, chain, n)
+			}
+			prefix, profile = name+".", ""
+			if strings.HasPrefix(name, profilePrefix) {
+				if profile = strings.TrimSpace(name[len(profilePrefix):]); profile == "" {
+					return nil, parseError(text, chain, n)
+				}
+				prefix = ""
+			}
+			l.section = name
+		case isInclude(text):
+			site := append([]frame{}, chain...)
+			site[len(site)-1].line = n
+			var e errors.Error
+			if included, e = p.include(strings.TrimSpace(text[len("include"):]), site); e != nil {
+				return nil, e
+			}
+		default:
+			i := strings.Index(text, "=")
+			if i < 0 {
+				return nil, parseError(text, chain, n)
+			}
+			key := strings.TrimSpace(text[:i])
+			l.profile = profile
+			if j := strings.LastIndex(key, "@"); j >= 0 {
+				key, l.profile = strings.TrimSpace(key[:j]), strings.TrimSpace(key[j+1:])
+				if key == "" || l.profile == "" {
+					return nil, parseError(text, chain, n)
+				}
+			}
+			l.key = prefix + key
+			if strings.HasSuffix(key, "[]") {
+				// The next element of the list.
+				if key == "[]" {
+					return nil, parseError(text, chain, n)
+				}
+				l.list = strings.TrimSuffix(l.key, "[]")
+				l.key = p.element(l.profile, l.list)
+			}
+			l.value = strings.TrimSpace(text[i+1:])
+
+			s := p.profile(l.profile)
+			s.options[l.key] = l.value
+			s.positions[l.key] = position{file: fname, line: n}
+		}
+		lines = append(lines, l)
+		lines = append(lines, included...)
+
+		if err == io.EOF {
+			break
+		}
+	}
+	return lines, nil
+}
+
+// Inner method, parse the files matched by the include pattern.
+// The option lines of the included files are returned as hidden lines,
+// so that they are not written back into the including file.
+func (p *parser) include(pattern string, chain []frame) ([]line, errors.Error) {
+	pattern = p.resolve(pattern, chain[len(chain)-1].name)
+
+	matches := []string{pattern}
+	if strings.ContainsAny(pattern, "*?[") {
+		var err error
+		if p.fsys != nil {
+			matches, err = fs.Glob(p.fsys, pattern)
+		} else {
+			matches, err = filepath.Glob(pattern)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "bad include pattern %s%s", pattern, includedFrom(chain))
+		}
+		sort.Strings(matches)
+	}
+
+	hidden := []line{}
+	for _, m := range matches {
+		lines, err := p.parseFile(m, chain)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range lines {
+			if l.key != "" {
+				hidden = append(hidden, line{key: l.key, value: l.value, profile: l.profile, list: l.list, included: true})
+			}
+		}
+	}
+	return hidden, nil
+}
+
+// Inner method, resolve the include pattern relative to the including file.
+// In a fs.FS, the absolute pattern is relative to the root of the FS.
+func (p *parser) resolve(pattern string, fname string) string {
+	if p.fsys != nil {
+		if strings.HasPrefix(pattern, "/") {
+			return path.Clean(pattern[1:])
+		}
+		return path.Join(path.Dir(fname), pattern)
+	}
+	if filepath.IsAbs(pattern) {
+		return pattern
+	}
+	return filepath.Join(filepath.Dir(fname), pattern)
+}
+
+// Inner method, whether the line is an "include path" directive.
+func isInclude(text string) bool {
+	if !strings.HasPrefix(text, "include") || len(text) == len("include") {
+		return false
+	}
+	rest := text[len("include"):]
+	return (rest[0] == ' ' || rest[0] == '\t') && strings.TrimSpace(rest)[0] != '='
+}
+
+// Inner method, parse the "[section]" header line.
+// The second return value is false if the line is not a valid header.
+func parseSection(line string) (string, bool) {
+	if len(line) < 2 || line[len(line)-1] != ']' {
+		return "", false
+	}
+	name := strings.TrimSpace(line[1 : len(line)-1])
+	if name == "" || strings.ContainsAny(name, "[]=") {
+		return "", false
+	}
+	return name, true
+}
+
+func parseError(text string, chain []frame, n int) errors.Error {
+	return errors.Newf("parse error: %s (%s:%d)%s",
+		text, chain[len(chain)-1].name, n, includedFrom(chain[:len(chain)-1]))
+}
+
+// Inner method, return the ", included from file:line" suffix of the chain.
+func includedFrom(chain []frame) string {
+	s := ""
+	for i := len(chain) - 1; i >= 0; i-- {
+		s += fmt.Sprintf(", included from %s:%d", chain[i].name, chain[i].line)
+	}
+	return s
+}
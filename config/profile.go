@@ -0,0 +1,118 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"github.com/roverli/utils/errors"
+	"os"
+	"strings"
+)
+
+// Profiles overlay the base options in one file:
+//
+//	[db]
+//	host = localhost
+//	host@prod = db.prod        # "db.host" in the "prod" profile
+//
+//	[profile:prod]             # the keys below belong to the "prod" profile
+//	log.level = warn
+//
+// The keys in a "[profile:name]" block are full keys, the block ends at the
+// next section header. The values of the active profiles override the base
+// values, a later active profile overrides an earlier one. The options of the
+// inactive profiles are ignored.
+
+// The section name prefix of a profile block.
+const profilePrefix = "profile:"
+
+// The environment variable of the comma separated active profiles.
+const ProfileEnv = "APP_PROFILE"
+
+// LoadProfile reads options from the file as Load, and activates the profiles
+// in order. If no profile is given, the profiles in the APP_PROFILE
+// environment variable are activated, which is what Load does.
+func LoadProfile(fname string, profiles ...string) (*Config, errors.Error) {
+	if len(profiles) == 0 {
+		return Load(fname)
+	}
+
+	file, err := os.Open(fname)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cann't open file %s", fname)
+	}
+	defer file.Close()
+
+	return parseConfig(newParser(nil), file, fname, profiles)
+}
+
+// ActiveProfiles gets the profiles applied to the config in order. A profile
+// is listed only if it's activated and defined in the loaded files, so a
+// misspelled profile name is not listed.
+func (c *Config) ActiveProfiles() []string {
+	return append([]string{}, c.profiles...)
+}
+
+// Inner method, return the profiles in the APP_PROFILE environment variable.
+func envProfiles() []string {
+	profiles := []string{}
+	for _, p := range strings.Split(os.Getenv(ProfileEnv), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			profiles = append(profiles, p)
+		}
+	}
+	return profiles
+}
+
+// Inner method, whether the profile of a line is active, the base lines are.
+func (c *Config) isActive(profile string) bool {
+	if profile == "" {
+		return true
+	}
+	for _, p := range c.profiles {
+		if p == profile {
+			return true
+		}
+	}
+	return false
+}
+
+// Inner method, return the options of the profile, the base options for "".
+func (p *parser) profile(name string) *snapshot {
+	if name == "" {
+		return &snapshot{options: p.options, positions: p.positions}
+	}
+	s, ok := p.profiles[name]
+	if !ok {
+		s = &snapshot{
+			options:   make(map[string]string),
+			positions: make(map[string]position),
+		}
+		p.profiles[name] = s
+	}
+	return s
+}
+
+// Inner method, override the base options by the profiles in order, the
+// lists of the "key[]" lines in a profile replace the base lists.
+// Return the profiles which are defined and applied.
+func (p *parser) apply(names []string) []string {
+	applied := []string{}
+	for _, name := range names {
+		s, ok := p.profiles[name]
+		if !ok || contains(applied, name) {
+			continue
+		}
+		base := p.profile("")
+		for list := range p.lists[name] {
+			removeList(base, list)
+		}
+		for k, v := range s.options {
+			p.options[k] = v
+			p.positions[k] = s.positions[k]
+		}
+		applied = append(applied, name)
+	}
+	return applied
+}
@@ -0,0 +1,90 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadProfile(t *testing.T) {
+	cases := []struct {
+		profiles []string
+		expected map[string]string
+		active   []string
+	}{
+		{nil, map[string]string{"db.host": "localhost", "db.pool": "", "log.level": ""}, []string{}},
+		{[]string{"dev"}, map[string]string{"db.host": "localhost", "db.pool": "2"}, []string{"dev"}},
+		{[]string{"prod"}, map[string]string{"db.host": "db.prod", "db.pool": "50", "log.level": "warn"}, []string{"prod"}},
+		{[]string{"prod", "eu", "qa"}, map[string]string{"db.host": "db.eu", "db.port": "3306"}, []string{"prod", "eu"}},
+		{[]string{"eu", "prod"}, map[string]string{"db.host": "db.prod"}, []string{"eu", "prod"}},
+	}
+	for _, c := range cases {
+		conf, err := LoadProfile("testdata/profile.conf", c.profiles...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, expected := range c.expected {
+			if v := conf.String(k, ""); v != expected {
+				t.Errorf("Expected %s to be %q in %v, but was %q", k, expected, c.profiles, v)
+			}
+		}
+		if v := conf.ActiveProfiles(); !reflect.DeepEqual(v, c.active) {
+			t.Errorf("Expected active profiles %v, but was %v", c.active, v)
+		}
+	}
+}
+
+func TestProfileEnv(t *testing.T) {
+	t.Setenv(ProfileEnv, "prod, eu")
+	conf, err := Load("testdata/profile.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := conf.ActiveProfiles(); !reflect.DeepEqual(v, []string{"prod", "eu"}) {
+		t.Errorf("Expected profiles from env, but was %v", v)
+	}
+	if v := conf.Explain("db.host"); v != "db.host = db.eu (testdata/profile.conf:15)" {
+		t.Errorf("Expected db.host from eu profile, but was %v", v)
+	}
+}
+
+func TestProfileWriteTo(t *testing.T) {
+	conf, err := LoadProfile("testdata/profile.conf", "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.SetOption("db.host", "db2.prod")
+	conf.SetOption("db.port", "3307")
+
+	var buf bytes.Buffer
+	if _, err := conf.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"host = localhost\n", "host@prod = db2.prod\n", "port = 3307\n", "pool@dev = 2\n", "db.host = db.eu\n"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Expected written config contains %q, but was:\n%s", want, buf.String())
+		}
+	}
+
+	conf.ClearOption("db.host")
+	buf.Reset()
+	if _, err := conf.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "localhost") || strings.Contains(buf.String(), "host@prod") {
+		t.Errorf("Expected db.host removed, but was:\n%s", buf.String())
+	}
+}
+
+func TestProfileParseError(t *testing.T) {
+	for _, content := range []string{"[profile:]\n", "@prod = 1\n", "a@ = 1\n"} {
+		if _, err := LoadReader(strings.NewReader(content), "bad.conf"); err == nil {
+			t.Errorf("Expected parse error of %q, but was nil", content)
+		}
+	}
+}
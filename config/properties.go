@@ -0,0 +1,264 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/roverli/utils/errors"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// LoadProperties reads options from the Java .properties file.
+//
+// The full java.util.Properties grammar is supported: "#" and "!" comments,
+// "=", ":" or whitespace separators, "\" line continuations, the "\t", "\n",
+// "\f", "\r" and "\uXXXX" escapes, and escaped separators in keys such as
+// "a\=b = c". The file is read as UTF-8.
+func LoadProperties(fname string) (*Config, errors.Error) {
+	file, err := os.Open(fname)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cann't open file %s", fname)
+	}
+	defer file.Close()
+
+	return migrated(parseProperties(file, fname))
+}
+
+// Inner method, parse the properties content, name is used in the error messages.
+func parseProperties(r io.Reader, name string) (*Config, errors.Error) {
+	s := &snapshot{
+		options:   make(map[string]string),
+		positions: make(map[string]position),
+	}
+
+	reader := bufio.NewReader(r)
+	n := 0
+	for {
+		logical, start, eof, err := readLogicalLine(reader, &n)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read file %s error.", name)
+		}
+		if logical != "" {
+			key, value, err := splitProperty(logical)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parse error: %s (%s:%d)", logical, name, start)
+			}
+			s.options[key] = value
+			s.positions[key] = position{file: name, line: start}
+		}
+		if eof {
+			break
+		}
+	}
+	return newConfig(s), nil
+}
+
+// Inner method, read the next logical line, the continuation lines are joined.
+// Comment and blank lines are returned as "". n counts the natural lines,
+// start is the number of the first natural line of the logical line.
+func readLogicalLine(reader *bufio.Reader, n *int) (logical string, start int, eof bool, err error) {
+	var b strings.Builder
+	continued := false
+	for {
+		raw, e := reader.ReadString('\n')
+		if e != nil && e != io.EOF {
+			return "", 0, false, e
+		}
+		eof = e == io.EOF
+		if eof && raw == "" && !continued {
+			return "", 0, true, nil
+		}
+		*n++
+
+		text := strings.TrimLeft(strings.TrimRight(raw, "\r\n"), " \t\f")
+		if !continued {
+			start = *n
+			if text == "" || text[0] == '#' || text[0] == '!' {
+				return "", start, eof, nil
+			}
+		}
+
+		// An odd number of trailing "\" continues the line.
+		slashes := len(text) - len(strings.TrimRight(text, "\\"))
+		if slashes%2 == 1 && !eof {
+			b.WriteString(text[:len(text)-1])
+			continued = true
+			continue
+		}
+		if slashes%2 == 1 {
+			text = text[:len(text)-1]
+		}
+		b.WriteString(text)
+		return b.String(), start, eof, nil
+	}
+}
+
+// Inner method, split the logical line into the unescaped key and value.
+func splitProperty(logical string) (string, string, error) {
+	i := 0
+	for ; i < len(logical); i++ {
+		ch := logical[i]
+		if ch == '\\' {
+			i++
+			continue
+		}
+		if ch == '=' || ch == ':' || ch == ' ' || ch == '\t' || ch == '\f' {
+			break
+		}
+	}
+	if i > len(logical) {
+		i = len(logical)
+	}
+	key := logical[:i]
+
+	rest := strings.TrimLeft(logical[i:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+
+	k, err := unescapeProperty(key)
+	if err != nil {
+		return "", "", err
+	}
+	v, err := unescapeProperty(rest)
+	if err != nil {
+		return "", "", err
+	}
+	return k, v, nil
+}
+
+// Inner method, resolve the escapes in the key or value.
+func unescapeProperty(s string) (string, error) {
+	if !strings.Contains(s, "\\") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'f':
+			b.WriteByte('\f')
+		case 'r':
+			b.WriteByte('\r')
+		case 'u':
+			r, size, err := unescapeUnicode(s[i-1:])
+			if err != nil {
+				return "", err
+			}
+			b.WriteRune(r)
+			i += size - 2
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// Inner method, decode the "\uXXXX" escape at the start of s, a surrogate
+// pair "\uXXXX\uXXXX" is decoded as one rune. Return the rune and the length
+// of the escape.
+func unescapeUnicode(s string) (rune, int, error) {
+	hex := func(s string) (rune, error) {
+		if len(s) < 6 || s[0] != '\\' || s[1] != 'u' {
+			return 0, fmt.Errorf("malformed \\uXXXX escape")
+		}
+		v, err := strconv.ParseUint(s[2:6], 16, 16)
+		if err != nil {
+			return 0, fmt.Errorf("malformed \\uXXXX escape %q", s[:6])
+		}
+		return rune(v), nil
+	}
+
+	r1, err := hex(s)
+	if err != nil {
+		return 0, 0, err
+	}
+	if utf16.IsSurrogate(r1) {
+		if r2, err := hex(s[6:]); err == nil {
+			if r := utf16.DecodeRune(r1, r2); r != unicode.ReplacementChar {
+				return r, 12, nil
+			}
+		}
+	}
+	return r1, 6, nil
+}
+
+// WriteProperties writes the options to w in the Java .properties format.
+// The keys are written in order, the special chars are escaped and the
+// non-ASCII chars are written as "\uXXXX", so that the output can be read
+// by java.util.Properties.load in any encoding.
+func (c *Config) WriteProperties(w io.Writer) errors.Error {
+	s := c.load()
+	keys := make([]string, 0, len(s.options))
+	for k := range s.options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	bw := bufio.NewWriter(w)
+	for _, k := range keys {
+		bw.WriteString(escapeProperty(k, true))
+		bw.WriteByte('=')
+		bw.WriteString(escapeProperty(s.options[k], false))
+		bw.WriteByte('\n')
+	}
+	if err := bw.Flush(); err != nil {
+		return errors.Wrap(err, "write properties error.")
+	}
+	return nil
+}
+
+// Inner method, escape the key or value. All the spaces in a key are escaped,
+// only the leading space in a value is escaped.
+func escapeProperty(s string, isKey bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '=', ':', '#', '!':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case ' ':
+			if isKey || i == 0 {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(' ')
+		default:
+			if r < 0x20 || r > 0x7e {
+				for _, u := range utf16.Encode([]rune{r}) {
+					fmt.Fprintf(&b, `\u%04X`, u)
+				}
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,87 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLoadProperties(t *testing.T) {
+	conf, err := LoadProperties("testdata/app.properties")
+	if err != nil {
+		t.Fatalf("Expected load app.properties no error, but was %v", err.Message())
+	}
+
+	cases := map[string]string{
+		"db.host":         "db.local",
+		"db.port":         "3306",
+		"name":            "Tom Cat",
+		"a=b:c":           "equal",
+		"path":            `c:\apps\app`,
+		"welcome":         "Hello, World",
+		"unicode":         "你好 😀",
+		"tabs":            "a\tb",
+		"empty":           "",
+		"indented":        "yes",
+		"key with spaces": "v",
+	}
+	for k, expected := range cases {
+		if v, ok := conf.load().lookup(k); !ok || v != expected {
+			t.Errorf("Expected %q to be %q, but was %q", k, expected, v)
+		}
+	}
+	if len(conf.Keys()) != len(cases) {
+		t.Errorf("Expected %d keys, but was %q", len(cases), conf.Keys())
+	}
+
+	if v := conf.Explain("welcome"); v != "welcome = Hello, World (testdata/app.properties:8)" {
+		t.Errorf("Expected welcome defined at line 8, but was %s", v)
+	}
+}
+
+func TestPropertiesRoundTrip(t *testing.T) {
+	conf, err := LoadProperties("testdata/app.properties")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.SetOption("lead", " space#!")
+
+	var buf bytes.Buffer
+	if err := conf.WriteProperties(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`a\=b\:c=equal`,
+		`key\ with\ spaces=v`,
+		`lead=\ space\#\!`,
+		`path=c\:\\apps\\app`,
+		`unicode=\u4F60\u597D \uD83D\uDE00`,
+	} {
+		if !strings.Contains(buf.String(), want+"\n") {
+			t.Errorf("Expected written properties to contain %q, but was:\n%s", want, buf.String())
+		}
+	}
+
+	again, err := parseProperties(&buf, "buffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range conf.Keys() {
+		v1, _ := conf.load().lookup(k)
+		v2, _ := again.load().lookup(k)
+		if v1 != v2 {
+			t.Errorf("Expected %q to round trip as %q, but was %q", k, v1, v2)
+		}
+	}
+}
+
+func TestPropertiesBadEscape(t *testing.T) {
+	_, err := parseProperties(strings.NewReader("a = 1\nb = \\u12x4\n"), "bad.properties")
+	if err == nil || !strings.Contains(err.Message(), "bad.properties:2") {
+		t.Errorf("Expected malformed escape error at line 2, but was %v", err)
+	}
+}
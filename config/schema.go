@@ -0,0 +1,426 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/roverli/utils/errors"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Type is the value type of a schema field.
+type Type int
+
+const (
+	TypeString Type = iota
+	TypeBool
+	TypeInt
+	TypeFloat
+	TypeDuration
+	TypeBytes
+	TypeTime
+	TypeList
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeBool:
+		return "bool"
+	case TypeInt:
+		return "int"
+	case TypeFloat:
+		return "float"
+	case TypeDuration:
+		return "duration"
+	case TypeBytes:
+		return "bytes"
+	case TypeTime:
+		return "time"
+	case TypeList:
+		return "list"
+	}
+	return "string"
+}
+
+// Schema declares the expected keys of a config, e.g.
+//
+//	schema := config.NewSchema()
+//	schema.Key("db.host", config.TypeString).Required().Doc("The database host.")
+//	schema.Key("db.port", config.TypeInt).Default("3306").Range(1, 65535)
+//	schema.Key("log.level", config.TypeString).Default("info").OneOf("debug", "info", "error")
+//	schema.Key("name", config.TypeString).Match(`^[a-z][a-z0-9_]*$`)
+//
+//	if err := schema.Validate(conf); err != nil {
+//		log.Fatal(err.Message())
+//	}
+type Schema struct {
+	fields []*Field
+	byKey  map[string]*Field
+}
+
+// Field declares one key of the schema, the methods return the field itself
+// so that the constraints can be chained.
+type Field struct {
+	key        string
+	typ        Type
+	required   bool
+	defaultv   string
+	hasDefault bool
+	allowed    []string
+	min, max   *float64
+	pattern    *regexp.Regexp
+	doc        string
+}
+
+// NewSchema returns an empty schema.
+func NewSchema() *Schema {
+	return &Schema{byKey: make(map[string]*Field)}
+}
+
+// Key declares the key with the type, declaring a key again replaces it.
+func (s *Schema) Key(key string, typ Type) *Field {
+	f := &Field{key: key, typ: typ}
+	if old, ok := s.byKey[key]; ok {
+		*old = *f
+		return old
+	}
+	s.fields = append(s.fields, f)
+	s.byKey[key] = f
+	return f
+}
+
+// Required marks the key must be set.
+func (f *Field) Required() *Field {
+	f.required = true
+	return f
+}
+
+// Default sets the default value, it's used by ApplyDefaults and WriteSample.
+func (f *Field) Default(v string) *Field {
+	f.defaultv = v
+	f.hasDefault = true
+	return f
+}
+
+// OneOf limits the value to the allowed values. Each element of a list is checked.
+func (f *Field) OneOf(values ...string) *Field {
+	f.allowed = values
+	return f
+}
+
+// Range limits the numeric value to [min, max]. It's checked on the int, float
+// and bytes values, and on the seconds of the duration values.
+func (f *Field) Range(min, max float64) *Field {
+	f.min, f.max = &min, &max
+	return f
+}
+
+// Min limits the numeric value to be at least min, see Range.
+func (f *Field) Min(min float64) *Field {
+	f.min = &min
+	return f
+}
+
+// Max limits the numeric value to be at most max, see Range.
+func (f *Field) Max(max float64) *Field {
+	f.max = &max
+	return f
+}
+
+// Match limits the value to match the regular expression, the expression
+// must be valid. Each element of a list is checked.
+func (f *Field) Match(expr string) *Field {
+	f.pattern = regexp.MustCompile(expr)
+	return f
+}
+
+// Doc sets the description written by WriteSample.
+func (f *Field) Doc(doc string) *Field {
+	f.doc = doc
+	return f
+}
+
+// Validate checks the config against the schema. All the violations are
+// reported in one error:
+//
+//   - a required key is missing
+//   - a value cann't be converted to the type of its key
+//   - a value is not one of the allowed values, out of range or doesn't match
+//   - an unknown key is probably a typo of a declared key, e.g. "db.max_conn"
+//     for "db.max_conns"
+//
+// The other unknown keys are ignored. It returns nil if the config is valid.
+func (s *Schema) Validate(c *Config) errors.Error {
+	snap := c.load()
+	var problems []string
+	for _, f := range s.fields {
+		v, ok, secret, err := snap.getSecret(f.key)
+		if err != nil {
+			problems = append(problems, err.Message())
+			continue
+		}
+		if !ok {
+			if f.required {
+				problems = append(problems, fmt.Sprintf("missing required key %s", f.key))
+			}
+			continue
+		}
+		if e := f.check(v); e != nil {
+			msg := e.Error()
+			if secret {
+				msg = fmt.Sprintf("invalid %s value %s", f.typ, redacted)
+			}
+			problems = append(problems, fmt.Sprintf("key %s: %s%s", f.key, msg, snap.where(f.key)))
+		}
+	}
+
+	keys := make([]string, 0, len(snap.options))
+	for k := range snap.options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	known := make([]string, 0, len(s.fields))
+	for _, f := range s.fields {
+		known = append(known, f.key)
+	}
+	for _, k := range keys {
+		if _, ok := s.byKey[k]; ok {
+			continue
+		}
+		if guess := suggest(k, known); guess != "" {
+			problems = append(problems, fmt.Sprintf("unknown key %s%s, did you mean %s?", k, snap.where(k), guess))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.Newf("config: validate failed:\n\t%s", strings.Join(problems, "\n\t"))
+	}
+	return nil
+}
+
+// Inner method, check the value against the type and constraints of the field.
+func (f *Field) check(v string) error {
+	var n float64
+	var numeric bool
+	var err error
+	switch f.typ {
+	case TypeBool:
+		if _, ok := parseBool(v); !ok {
+			err = strconv.ErrSyntax
+		}
+	case TypeInt:
+		var i int64
+		i, err = parseInt(v, 64)
+		n, numeric = float64(i), true
+	case TypeFloat:
+		n, err = strconv.ParseFloat(v, 64)
+		numeric = true
+	case TypeDuration:
+		var d time.Duration
+		d, err = time.ParseDuration(v)
+		n, numeric = d.Seconds(), true
+	case TypeBytes:
+		var b int64
+		b, err = parseBytes(v)
+		n, numeric = float64(b), true
+	case TypeTime:
+		_, err = time.Parse(time.RFC3339, v)
+	}
+	if err != nil {
+		return fmt.Errorf("cann't convert %q to %s", v, f.typ)
+	}
+
+	if numeric && f.min != nil && n < *f.min {
+		return fmt.Errorf("%s is less than %v", v, *f.min)
+	}
+	if numeric && f.max != nil && n > *f.max {
+		return fmt.Errorf("%s is greater than %v", v, *f.max)
+	}
+
+	values := []string{v}
+	if f.typ == TypeList {
+		if values, err = splitList(v); err != nil {
+			return fmt.Errorf("cann't convert %q to list", v)
+		}
+	}
+	for _, e := range values {
+		if f.allowed != nil && !contains(f.allowed, e) {
+			return fmt.Errorf("%q is not one of %s", e, strings.Join(f.allowed, ", "))
+		}
+		if f.pattern != nil && !f.pattern.MatchString(e) {
+			return fmt.Errorf("%q doesn't match %s", e, f.pattern)
+		}
+	}
+	return nil
+}
+
+func contains(values []string, v string) bool {
+	for _, e := range values {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyDefaults sets the default values of the missing keys in the config.
+func (s *Schema) ApplyDefaults(c *Config) {
+	c.update(func(snap *snapshot) {
+		for _, f := range s.fields {
+			if _, ok := snap.lookup(f.key); !ok && f.hasDefault {
+				snap.options[f.key] = f.defaultv
+			}
+		}
+	})
+}
+
+// WriteSample writes a commented sample config file of the schema to w.
+// The keys are grouped into "[section]" blocks by the part before the first
+// ".", in the declaration order. The keys with a default value are written
+// with it, the required keys are written with an empty value, and the other
+// keys are commented out, e.g.
+//
+//	[db]
+//	# The database host.
+//	# string, required
+//	host =
+//
+//	# int, range [1, 65535]
+//	port = 3306
+func (s *Schema) WriteSample(w io.Writer) errors.Error {
+	var sections []string
+	groups := make(map[string][]*Field)
+	for _, f := range s.fields {
+		section := ""
+		if i := strings.Index(f.key, "."); i > 0 {
+			section = f.key[:i]
+		}
+		if _, ok := groups[section]; !ok {
+			sections = append(sections, section)
+		}
+		groups[section] = append(groups[section], f)
+	}
+	// The keys without a section must be written before any "[section]".
+	sort.SliceStable(sections, func(i, j int) bool {
+		return sections[i] == "" && sections[j] != ""
+	})
+
+	bw := bufio.NewWriter(w)
+	for i, section := range sections {
+		if i > 0 {
+			bw.WriteString("\n")
+		}
+		if section != "" {
+			bw.WriteString("[" + section + "]\n")
+		}
+		for j, f := range groups[section] {
+			if j > 0 {
+				bw.WriteString("\n")
+			}
+			f.writeSample(bw, section)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return errors.Wrap(err, "write sample error.")
+	}
+	return nil
+}
+
+// Inner method, write the comments and the line of the field.
+func (f *Field) writeSample(bw *bufio.Writer, section string) {
+	if f.doc != "" {
+		for _, line := range strings.Split(f.doc, "\n") {
+			bw.WriteString("# " + line + "\n")
+		}
+	}
+
+	attrs := []string{f.typ.String()}
+	if f.required {
+		attrs = append(attrs, "required")
+	}
+	if f.allowed != nil {
+		attrs = append(attrs, "one of "+strings.Join(f.allowed, ", "))
+	}
+	switch {
+	case f.min != nil && f.max != nil:
+		attrs = append(attrs, fmt.Sprintf("range [%v, %v]", *f.min, *f.max))
+	case f.min != nil:
+		attrs = append(attrs, fmt.Sprintf("min %v", *f.min))
+	case f.max != nil:
+		attrs = append(attrs, fmt.Sprintf("max %v", *f.max))
+	}
+	if f.pattern != nil {
+		attrs = append(attrs, "match "+f.pattern.String())
+	}
+	bw.WriteString("# " + strings.Join(attrs, ", ") + "\n")
+
+	key := f.key
+	if section != "" {
+		key = key[len(section)+1:]
+	}
+	switch {
+	case f.hasDefault:
+		bw.WriteString(key + " = " + f.defaultv + "\n")
+	case f.required:
+		bw.WriteString(key + " =\n")
+	default:
+		bw.WriteString("# " + key + " =\n")
+	}
+}
+
+// Inner method, return the candidate which is probably the misspelling of key,
+// or "" if no candidate is close enough. A candidate is close if the edit
+// distance is at most 1 for the short keys and 2 for the keys longer than 6.
+func suggest(key string, candidates []string) string {
+	best, bestDist := "", -1
+	for _, c := range candidates {
+		limit := 1
+		if len(c) > 6 {
+			limit = 2
+		}
+		d := editDistance(key, c)
+		if d <= limit && (bestDist < 0 || d < bestDist) {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+// Inner method, return the Levenshtein distance of a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,141 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testSchema() *Schema {
+	schema := NewSchema()
+	schema.Key("name", TypeString).Required().Match(`^[a-z]+$`).Doc("The application name.")
+	schema.Key("mode", TypeString).Default("safe").OneOf("safe", "normal")
+	schema.Key("db.host", TypeString).Required()
+	schema.Key("db.port", TypeInt).Default("3306").Range(1, 65535)
+	schema.Key("db.max_conns", TypeInt).Min(1)
+	schema.Key("db.timeout", TypeDuration).Max(60)
+	schema.Key("db.tags", TypeList).OneOf("a", "b")
+	schema.Key("db.user", TypeString).Required()
+	return schema
+}
+
+func TestSchemaValidate(t *testing.T) {
+	conf, err := Load("testdata/schema.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = testSchema().Validate(conf)
+	if err == nil {
+		t.Fatal("Expected validate error, but was nil")
+	}
+	for _, want := range []string{
+		`key name: "App" doesn't match ^[a-z]+$ (testdata/schema.conf:1)`,
+		`key mode: "fast" is not one of safe, normal (testdata/schema.conf:2)`,
+		`key db.port: 70000 is greater than 65535 (testdata/schema.conf:6)`,
+		`key db.timeout: 2m is greater than 60 (testdata/schema.conf:8)`,
+		`key db.tags: "x" is not one of a, b (testdata/schema.conf:9)`,
+		"missing required key db.user",
+		"unknown key db.max_conn (testdata/schema.conf:7), did you mean db.max_conns?",
+	} {
+		if !strings.Contains(err.Message(), want) {
+			t.Errorf("Expected error contains %q, but was %s", want, err.Message())
+		}
+	}
+	if strings.Contains(err.Message(), "db.host") {
+		t.Errorf("Expected db.host is valid, but was %s", err.Message())
+	}
+}
+
+func TestSchemaValidOK(t *testing.T) {
+	conf := New()
+	conf.SetOption("name", "app")
+	conf.SetOption("db.host", "localhost")
+	conf.SetOption("db.user", "root")
+	conf.SetOption("db.port", "abc")
+	conf.SetOption("unrelated.key", "1")
+
+	schema := testSchema()
+	err := schema.Validate(conf)
+	if err == nil || !strings.Contains(err.Message(), `key db.port: cann't convert "abc" to int`) {
+		t.Errorf("Expected db.port int error, but was %v", err)
+	}
+
+	conf.ClearOption("db.port")
+	if err := schema.Validate(conf); err != nil {
+		t.Errorf("Expected valid config, but was %v", err.Message())
+	}
+
+	schema.ApplyDefaults(conf)
+	if v := conf.String("db.port", ""); v != "3306" {
+		t.Errorf("Expected db.port default 3306, but was %v", v)
+	}
+	if v := conf.String("mode", ""); v != "safe" {
+		t.Errorf("Expected mode default safe, but was %v", v)
+	}
+}
+
+func TestSchemaWriteSample(t *testing.T) {
+	schema := NewSchema()
+	schema.Key("db.host", TypeString).Required().Doc("The database host.")
+	schema.Key("name", TypeString).Default("app")
+	schema.Key("db.port", TypeInt).Default("3306").Range(1, 65535)
+	schema.Key("log.level", TypeString).OneOf("debug", "info")
+
+	var buf bytes.Buffer
+	if err := schema.WriteSample(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# string
+name = app
+
+[db]
+# The database host.
+# string, required
+host =
+
+# int, range [1, 65535]
+port = 3306
+
+[log]
+# string, one of debug, info
+# level =
+`
+	if buf.String() != expected {
+		t.Errorf("Expected sample:\n%s\nbut was:\n%s", expected, buf.String())
+	}
+
+	fname := filepath.Join(t.TempDir(), "sample.conf")
+	if err := os.WriteFile(fname, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := Load(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := conf.String("db.port", ""); v != "3306" {
+		t.Errorf("Expected sample db.port 3306, but was %v", v)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	known := []string{"db.host", "db.port", "db.max_conns", "name"}
+	cases := map[string]string{
+		"db.hots":     "db.host",
+		"db.max_conn": "db.max_conns",
+		"nmae":        "",
+		"nam":         "name",
+		"cache.addr":  "",
+	}
+	for k, expected := range cases {
+		if v := suggest(k, known); v != expected {
+			t.Errorf("Expected suggest %s to be %q, but was %q", k, expected, v)
+		}
+	}
+}
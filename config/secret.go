@@ -0,0 +1,237 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"github.com/roverli/utils/errors"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Secret values in the options:
+//
+//	password = ENC(base64...)           decrypted by the KeyProvider of the config
+//	password_FILE = /run/secrets/db     the content of the file is the value of
+//	                                    "password" if "password" is not set
+//
+// Only the upper case "_FILE" suffix names a secret file, so the common keys
+// such as "log_file" or "pid_file" are plain values.
+//
+// The secrets are decrypted transparently by String and the typed getters.
+// They are not expanded, a "${" in a secret is literal, but the values which
+// reference a secret are secrets too. The secrets are redacted in Dump,
+// Explain and the error messages. WriteTo, Save and the other writers keep the
+// ENC(...) values encrypted, and never write the content of the secret files.
+
+// The text shown in place of a secret value.
+const redacted = "******"
+
+// The suffix of the key which names the secret file of a key.
+const secretSuffix = "_FILE"
+
+// KeyProvider decrypts the ENC(...) values, the ciphertext is the base64
+// decoded text between the parentheses.
+type KeyProvider interface {
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// SetKeyProvider sets the provider to decrypt the ENC(...) values.
+func (c *Config) SetKeyProvider(p KeyProvider) {
+	c.update(func(s *snapshot) {
+		s.provider = p
+	})
+}
+
+// Secret is a string which is redacted when it's printed or marshaled,
+// use it to hold the secrets so that they don't leak into the logs.
+type Secret string
+
+// String returns the redacted text, use Reveal to get the value.
+func (s Secret) String() string {
+	return redacted
+}
+
+// GoString returns the redacted text for "%#v".
+func (s Secret) GoString() string {
+	return redacted
+}
+
+// MarshalText returns the redacted text, it's used by the JSON encoder too.
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(redacted), nil
+}
+
+// Reveal returns the secret value.
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+// Secret gets the value for the given key as a Secret, see String.
+func (c *Config) Secret(key string, defaultv Secret) Secret {
+	c.touch(key)
+	v, ok, err := c.load().get(key)
+	if !ok || err != nil {
+		return defaultv
+	}
+	return Secret(v)
+}
+
+// IsSecret checks whether the value of the key is a secret.
+func (c *Config) IsSecret(key string) bool {
+	_, _, secret, _ := c.load().getSecret(key)
+	return secret
+}
+
+// Dump writes the sorted "key = value" lines of the expanded values to w,
+// the secrets are redacted. It's intended for the debug output.
+func (c *Config) Dump(w io.Writer) errors.Error {
+	s := c.load()
+	keys := s.keys()
+	sort.Strings(keys)
+
+	bw := bufio.NewWriter(w)
+	for _, k := range keys {
+		v, _, secret, err := s.getSecret(k)
+		switch {
+		case secret:
+			v = redacted
+		case err != nil:
+			v, _ = s.lookup(k)
+		}
+		bw.WriteString(k + " = " + v + "\n")
+	}
+	if err := bw.Flush(); err != nil {
+		return errors.Wrap(err, "write dump error.")
+	}
+	return nil
+}
+
+// Inner method, find the raw value of the key. The ENC(...) value is decrypted
+// and the missing key is read from the file named by the "_FILE" key.
+// The third return value is true if the value is a secret.
+func (s *snapshot) fetch(key string) (string, bool, bool, errors.Error) {
+	v, ok := s.lookup(key)
+	if !ok {
+		fname, ok := s.lookup(key + secretSuffix)
+		if !ok {
+			return "", false, false, nil
+		}
+		content, err := os.ReadFile(fname)
+		if err != nil {
+			return "", true, true, errors.Wrapf(err, "config: key %s: cann't read secret file %s%s",
+				key, fname, s.where(key+secretSuffix))
+		}
+		return strings.TrimRight(string(content), "\r\n"), true, true, nil
+	}
+
+	ciphertext, ok := encrypted(v)
+	if !ok {
+		return v, true, false, nil
+	}
+	if s.provider == nil {
+		return "", true, true, errors.Newf("config: key %s: no key provider to decrypt the value%s", key, s.where(key))
+	}
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", true, true, errors.Newf("config: key %s: malformed encrypted value%s", key, s.where(key))
+	}
+	plain, err := s.provider.Decrypt(data)
+	if err != nil {
+		return "", true, true, errors.Newf("config: key %s: cann't decrypt the value%s", key, s.where(key))
+	}
+	return string(plain), true, true, nil
+}
+
+// Inner method, return the text between the parentheses of the ENC(...) value.
+func encrypted(v string) (string, bool) {
+	v = strings.TrimSpace(v)
+	if strings.HasPrefix(v, "ENC(") && strings.HasSuffix(v, ")") {
+		return v[len("ENC(") : len(v)-1], true
+	}
+	return "", false
+}
+
+// AESKeyProvider is the AES-GCM KeyProvider. The ciphertext is the random
+// nonce followed by the sealed data.
+type AESKeyProvider struct {
+	aead cipher.AEAD
+}
+
+// NewAESKeyProvider creates the provider with the 16, 24 or 32 bytes key,
+// for AES-128, AES-192 or AES-256.
+func NewAESKeyProvider(key []byte) (*AESKeyProvider, errors.Error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "config: invalid aes key.")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "config: invalid aes key.")
+	}
+	return &AESKeyProvider{aead: aead}, nil
+}
+
+// AESKeyFromFile creates the provider with the base64 encoded key in the file.
+func AESKeyFromFile(fname string) (*AESKeyProvider, errors.Error) {
+	content, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cann't open file %s", fname)
+	}
+	return newAESKeyProvider(string(content), "key file "+fname)
+}
+
+// AESKeyFromEnv creates the provider with the base64 encoded key in the
+// environment variable.
+func AESKeyFromEnv(name string) (*AESKeyProvider, errors.Error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return nil, errors.Newf("config: env %s is not set", name)
+	}
+	return newAESKeyProvider(v, "env "+name)
+}
+
+// Inner method, create the provider with the base64 encoded key from the source.
+func newAESKeyProvider(encoded string, source string) (*AESKeyProvider, errors.Error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, errors.Newf("config: malformed base64 key in %s", source)
+	}
+	return NewAESKeyProvider(key)
+}
+
+// Decrypt opens the nonce and sealed data.
+func (p *AESKeyProvider) Decrypt(ciphertext []byte) ([]byte, error) {
+	size := p.aead.NonceSize()
+	if len(ciphertext) < size {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	return p.aead.Open(nil, ciphertext[:size], ciphertext[size:], nil)
+}
+
+// Encrypt seals the plaintext with a random nonce.
+func (p *AESKeyProvider) Encrypt(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, p.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return p.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// EncryptValue encrypts the plaintext into the ENC(...) value for the config files.
+func (p *AESKeyProvider) EncryptValue(plaintext string) (string, errors.Error) {
+	data, err := p.Encrypt([]byte(plaintext))
+	if err != nil {
+		return "", errors.Wrap(err, "config: encrypt error.")
+	}
+	return "ENC(" + base64.StdEncoding.EncodeToString(data) + ")", nil
+}
@@ -0,0 +1,112 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// Run with "go test -race" to check the concurrent access.
+func TestConcurrentAccess(t *testing.T) {
+	conf := loadConfig(t)
+	other := New()
+	other.SetOption("city", "Tokyo")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				key := "k" + strconv.Itoa(i)
+				conf.SetOption(key, strconv.Itoa(j))
+				conf.Merge(other)
+				conf.ClearOption(key)
+			}
+		}(i)
+	}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				if v := conf.String("name", ""); v != "tom" {
+					t.Errorf(`Expected name to be "tom", but was %v`, v)
+				}
+				conf.Int("age", 0)
+				conf.Keys()
+				conf.Sections()
+				conf.Snapshot().Bool("man", false)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v := conf.String("city", ""); v != "Tokyo" || len(conf.Keys()) != 5 {
+		t.Errorf("Expected merged city and 5 keys, but was %v %v", v, conf.Keys())
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	conf := loadConfig(t)
+	snap := conf.Snapshot()
+
+	conf.SetOption("name", "li")
+	if v := snap.String("name", ""); v != "tom" {
+		t.Errorf(`Expected snapshot name to be "tom", but was %v`, v)
+	}
+
+	snap.SetOption("age", "30")
+	if v := conf.Int("age", 0); v != 25 {
+		t.Errorf("Expected config age not affected by snapshot, but was %v", v)
+	}
+}
+
+func BenchmarkString(b *testing.B) {
+	conf, _ := Load("testdata/read.conf")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		conf.String("name", "")
+	}
+}
+
+func BenchmarkStringParallel(b *testing.B) {
+	conf, _ := Load("testdata/read.conf")
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			conf.String("name", "")
+		}
+	})
+}
+
+func BenchmarkStringSub(b *testing.B) {
+	conf, _ := Load("testdata/read.conf")
+	conf.SetOption("db.name", "app")
+	sub := conf.Sub("db")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sub.String("name", "")
+	}
+}
+
+// The unsynchronized map read, as the baseline of BenchmarkString.
+func BenchmarkMapRead(b *testing.B) {
+	options := map[string]string{"name": "tom", "age": "25", "height": "1.7", "man": "1"}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = options["name"]
+	}
+}
+
+func BenchmarkSetOption(b *testing.B) {
+	conf, _ := Load("testdata/read.conf")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		conf.SetOption("name", "li")
+	}
+}
@@ -0,0 +1,254 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"flag"
+	"fmt"
+	"github.com/roverli/utils/errors"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Source is a layer of options for Layered.
+type Source interface {
+
+	// This returns the name shown by Config.Explain, e.g. "env", "file app.conf".
+	Name() string
+
+	// This returns the value of the key, the second return value is false
+	// if the source does not have the key.
+	Lookup(key string) (string, bool)
+
+	// This returns the keys the source knows. A source which can only answer
+	// Lookup, such as the environment, returns nil.
+	Keys() []string
+}
+
+// Layered creates a config from the sources, each key is resolved by the first
+// source that has it. So the sources are declared from the highest precedence
+// to the lowest, e.g.
+//
+//	config.Layered(flags, config.EnvSource("APP_"), file, defaults)
+//
+// The keys of the result are all the keys known by the sources, the values are
+// resolved once when Layered is called. Use Config.Explain to find out which
+// layer supplied a value.
+func Layered(sources ...Source) *Config {
+	s := &snapshot{
+		options:   make(map[string]string),
+		positions: make(map[string]position),
+	}
+	for _, src := range sources {
+		for _, k := range src.Keys() {
+			if _, ok := s.options[k]; ok {
+				continue
+			}
+			v, i := resolveLayers(sources, k)
+			s.options[k] = v
+			if cs, ok := sources[i].(*configSource); ok {
+				if p, ok := cs.conf.load().positions[k]; ok {
+					s.positions[k] = p
+				}
+			}
+		}
+	}
+
+	c := newConfig(s)
+	c.layers = sources
+	return c
+}
+
+// Inner method, return the value of the key from the first source that has it,
+// and the index of the source. Return -1 if none has it.
+func resolveLayers(sources []Source, key string) (string, int) {
+	for i, src := range sources {
+		if v, ok := src.Lookup(key); ok {
+			return v, i
+		}
+	}
+	return "", -1
+}
+
+// Explain describes where the value of the key comes from.
+// For a Layered config, every layer which has the key is listed in the
+// precedence order and the one in effect is marked by "*", e.g.
+//
+//	db.host = db.prod
+//	  * env: db.prod
+//	    file app.conf: localhost
+func (c *Config) Explain(key string) string {
+	s := c.load()
+	// The secrets are redacted, see Secret.
+	_, _, secret, _ := s.getSecret(key)
+	show := func(v string) string {
+		if _, ok := encrypted(v); ok || secret {
+			return redacted
+		}
+		return v
+	}
+
+	if fv, ok := s.flags[key]; ok {
+		return fmt.Sprintf("%s = %s (flag -%s)", key, show(fv), key)
+	}
+	if ev, ok := s.lookupEnv(key); ok {
+		if s.mapped() {
+			return fmt.Sprintf("%s = %s (env %s)", key, show(ev), s.envName(key))
+		}
+		if _, ok := s.options[key]; !ok {
+			return fmt.Sprintf("%s = %s (env)", key, show(ev))
+		}
+	}
+	v, ok := s.options[key]
+	if !ok {
+		if secret {
+			return fmt.Sprintf("%s = %s (secret file)", key, redacted)
+		}
+		return key + " is not set"
+	}
+
+	if len(c.layers) == 0 {
+		if p, ok := s.positions[key]; ok && p.file != "" {
+			return fmt.Sprintf("%s = %s (%s)", key, show(v), p)
+		}
+		return fmt.Sprintf("%s = %s (set)", key, show(v))
+	}
+
+	lines := []string{key + " = " + show(v)}
+	_, used := resolveLayers(c.layers, key)
+	for i, src := range c.layers {
+		lv, ok := src.Lookup(key)
+		if !ok {
+			continue
+		}
+		mark := "   "
+		if i == used {
+			mark = "  *"
+		}
+		lines = append(lines, fmt.Sprintf("%s %s: %s", mark, src.Name(), show(lv)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Inner source backed by a config.
+type configSource struct {
+	name string
+	conf *Config
+}
+
+func (s *configSource) Name() string {
+	return s.name
+}
+
+func (s *configSource) Lookup(key string) (string, bool) {
+	snap := s.conf.load()
+	if snap.mapped() {
+		return snap.lookup(key)
+	}
+	v, ok := snap.options[key]
+	return v, ok
+}
+
+func (s *configSource) Keys() []string {
+	keys := s.conf.Keys()
+	sort.Strings(keys)
+	return keys
+}
+
+// FileSource loads the file as a source.
+func FileSource(fname string) (Source, errors.Error) {
+	conf, err := Load(fname)
+	if err != nil {
+		return nil, err
+	}
+	return &configSource{name: "file " + fname, conf: conf}, nil
+}
+
+// ConfigSource uses the config as a source.
+// The options of conf are read when Layered is called and by Explain.
+func ConfigSource(name string, conf *Config) Source {
+	return &configSource{name: name, conf: conf}
+}
+
+// MapSource uses the map as a source, e.g. for the default values.
+func MapSource(name string, options map[string]string) Source {
+	conf := New()
+	conf.update(func(s *snapshot) {
+		for k, v := range options {
+			s.options[k] = v
+		}
+	})
+	return &configSource{name: name, conf: conf}
+}
+
+// Inner source backed by the environment variables.
+type envSource struct {
+	prefix string
+}
+
+// EnvSource uses the environment variables as a source.
+// The variable name of a key is the prefix followed by the upper case key,
+// with "." and "-" replaced by "_", e.g. "APP_" and "db.host" is "APP_DB_HOST".
+// An empty variable is treated as not set.
+// The source only answers the keys known by the other sources.
+func EnvSource(prefix string) Source {
+	return &envSource{prefix: prefix}
+}
+
+func (s *envSource) Name() string {
+	return "env"
+}
+
+func (s *envSource) Lookup(key string) (string, bool) {
+	v := os.Getenv(envName(s.prefix, key))
+	return v, v != ""
+}
+
+func (s *envSource) Keys() []string {
+	return nil
+}
+
+// Inner method, map the key to the environment variable name.
+func envName(prefix string, key string) string {
+	return prefix + strings.NewReplacer(".", "_", "-", "_").Replace(strings.ToUpper(key))
+}
+
+// Inner source backed by the explicitly set flags.
+type flagSource struct {
+	fs *flag.FlagSet
+}
+
+// FlagSource uses the flags set on the command line as a source, the flag
+// name is the key. The flags not set explicitly are ignored, so that their
+// default values don't shadow the lower layers. fs must be parsed first.
+func FlagSource(fs *flag.FlagSet) Source {
+	return &flagSource{fs: fs}
+}
+
+func (s *flagSource) Name() string {
+	return "flags"
+}
+
+func (s *flagSource) Lookup(key string) (string, bool) {
+	found := false
+	s.fs.Visit(func(f *flag.Flag) {
+		if f.Name == key {
+			found = true
+		}
+	})
+	if !found {
+		return "", false
+	}
+	return s.fs.Lookup(key).Value.String(), true
+}
+
+func (s *flagSource) Keys() []string {
+	keys := []string{}
+	s.fs.Visit(func(f *flag.Flag) {
+		keys = append(keys, f.Name)
+	})
+	return keys
+}
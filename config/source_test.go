@@ -0,0 +1,81 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestLayered(t *testing.T) {
+	t.Setenv("CONFIG_TEST_AGE", "30")
+	t.Setenv("CONFIG_TEST_DB_HOST", "db.env")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("name", "flag-default", "")
+	fs.String("city", "", "")
+	if err := fs.Parse([]string{"-city=Tokyo"}); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := FileSource("testdata/read.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaults := MapSource("defaults", map[string]string{
+		"name":    "nobody",
+		"db.host": "localhost",
+		"db.port": "3306",
+	})
+
+	conf := Layered(FlagSource(fs), EnvSource("CONFIG_TEST_"), file, defaults)
+
+	cases := map[string]string{
+		"city":    "Tokyo",
+		"name":    "tom",
+		"age":     "30",
+		"db.host": "db.env",
+		"db.port": "3306",
+		"height":  "1.7",
+	}
+	for k, expected := range cases {
+		if v := conf.String(k, ""); v != expected {
+			t.Errorf("Expected %s to be %q, but was %q", k, expected, v)
+		}
+	}
+	if len(conf.Keys()) != 7 {
+		t.Errorf("Expected 7 keys, but was %v", conf.Keys())
+	}
+
+	explain := conf.Explain("age")
+	expected := "age = 30\n  * env: 30\n    file testdata/read.conf: 25"
+	if explain != expected {
+		t.Errorf("Expected explain of age to be:\n%s\nbut was:\n%s", expected, explain)
+	}
+	if explain := conf.Explain("name"); !strings.Contains(explain, "  * file testdata/read.conf: tom") ||
+		!strings.Contains(explain, "    defaults: nobody") {
+		t.Errorf("Unexpected explain of name:\n%s", explain)
+	}
+
+	if p := conf.load().positions["height"]; p.line != 6 {
+		t.Errorf("Expected height position to be kept from the file, but was %v", p)
+	}
+}
+
+func TestExplainPlain(t *testing.T) {
+	conf := loadConfig(t)
+	conf.SetOption("city", "Tokyo")
+
+	if v := conf.Explain("name"); v != "name = tom (testdata/read.conf:4)" {
+		t.Errorf("Unexpected explain of name: %s", v)
+	}
+	if v := conf.Explain("city"); v != "city = Tokyo (set)" {
+		t.Errorf("Unexpected explain of city: %s", v)
+	}
+	if v := conf.Explain("nothing"); v != "nothing is not set" {
+		t.Errorf("Unexpected explain of nothing: %s", v)
+	}
+}
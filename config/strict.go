@@ -0,0 +1,146 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"github.com/roverli/utils/errors"
+	"time"
+)
+
+// The MustX getters are the strict form of the typed getters, they panic with
+// an errors.Error if the key does not exist or the value is malformed.
+// The error names the key, the raw value, the expected type and the
+// "file:line" where the key was defined, e.g.
+//
+//	config: key age: cann't convert "2o" to int (app.conf:3)
+//
+// Use them at the startup, a typo in the config file should stop the program
+// instead of silently falling back to a default value.
+
+// Inner representation of where a key was defined.
+type position struct {
+	file string
+	line int
+
+	// The key was merged from another config, file is empty if the key
+	// was not loaded from a file there.
+	merged bool
+}
+
+func (p position) String() string {
+	return fmt.Sprintf("%s:%d", p.file, p.line)
+}
+
+// Inner method, return the " (file:line)" suffix of the key for the error messages,
+// or an empty string if the key was not loaded from a file.
+func (s *snapshot) where(key string) string {
+	if p, ok := s.positions[key]; ok && p.file != "" {
+		return " (" + p.String() + ")"
+	}
+	return ""
+}
+
+// Inner method, panic if the key does not exist.
+func (c *Config) mustExist(key string) {
+	if _, ok, _, _ := c.load().fetch(key); !ok {
+		panic(errors.Newf("config: missing required key %s", key))
+	}
+}
+
+// Inner method, panic if err is not nil.
+func must(err errors.Error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+// MustString is the strict form of String.
+func (c *Config) MustString(key string) string {
+	c.mustExist(key)
+	v, err := c.StringE(key, "")
+	must(err)
+	return v
+}
+
+// MustBool is the strict form of Bool.
+func (c *Config) MustBool(key string) bool {
+	c.mustExist(key)
+	v, err := c.BoolE(key, false)
+	must(err)
+	return v
+}
+
+// MustFloat is the strict form of Float.
+func (c *Config) MustFloat(key string) float64 {
+	c.mustExist(key)
+	v, err := c.FloatE(key, 0)
+	must(err)
+	return v
+}
+
+// MustInt is the strict form of Int.
+func (c *Config) MustInt(key string) int {
+	c.mustExist(key)
+	v, err := c.IntE(key, 0)
+	must(err)
+	return v
+}
+
+// MustInt64 is the strict form of Int64.
+func (c *Config) MustInt64(key string) int64 {
+	c.mustExist(key)
+	v, err := c.Int64E(key, 0)
+	must(err)
+	return v
+}
+
+// MustUint64 is the strict form of Uint64.
+func (c *Config) MustUint64(key string) uint64 {
+	c.mustExist(key)
+	v, err := c.Uint64E(key, 0)
+	must(err)
+	return v
+}
+
+// MustDuration is the strict form of Duration.
+func (c *Config) MustDuration(key string) time.Duration {
+	c.mustExist(key)
+	v, err := c.DurationE(key, 0)
+	must(err)
+	return v
+}
+
+// MustBytes is the strict form of Bytes.
+func (c *Config) MustBytes(key string) int64 {
+	c.mustExist(key)
+	v, err := c.BytesE(key, 0)
+	must(err)
+	return v
+}
+
+// MustTime is the strict form of Time.
+func (c *Config) MustTime(key string) time.Time {
+	c.mustExist(key)
+	v, err := c.TimeE(key, time.Time{})
+	must(err)
+	return v
+}
+
+// MustStringSlice is the strict form of StringSlice.
+func (c *Config) MustStringSlice(key string) []string {
+	c.mustExist(key)
+	v, err := c.StringSliceE(key, nil)
+	must(err)
+	return v
+}
+
+// MustStringMap is the strict form of StringMap.
+func (c *Config) MustStringMap(key string) map[string]string {
+	c.mustExist(key)
+	v, err := c.StringMapE(key, nil)
+	must(err)
+	return v
+}
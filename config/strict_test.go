@@ -0,0 +1,79 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"github.com/roverli/utils/errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Call f and return the errors.Error it panics with.
+func catchPanic(f func()) (err errors.Error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = r.(errors.Error)
+		}
+	}()
+	f()
+	return nil
+}
+
+func TestMust(t *testing.T) {
+	conf, err := Load("testdata/strict.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v := conf.MustString("name"); v != "tom" {
+		t.Errorf(`Expected name to be "tom", but was %v`, v)
+	}
+
+	err = catchPanic(func() { conf.MustInt("age") })
+	if err == nil {
+		t.Fatal("Expected MustInt on age to panic.")
+	}
+	for _, want := range []string{"key age", `"2o"`, "int", "testdata/strict.conf:3"} {
+		if !strings.Contains(err.Message(), want) {
+			t.Errorf("Expected error to contain %q, but was %q", want, err.Message())
+		}
+	}
+
+	err = catchPanic(func() { conf.MustString("home") })
+	if err == nil || !strings.Contains(err.Message(), "testdata/strict.conf:4") {
+		t.Errorf("Expected MustString on home to panic with position, but was %v", err)
+	}
+
+	err = catchPanic(func() { conf.MustDuration("timeout") })
+	if err == nil || !strings.Contains(err.Message(), "missing required key timeout") {
+		t.Errorf("Expected MustDuration on timeout to panic with missing key, but was %v", err)
+	}
+}
+
+func TestPositions(t *testing.T) {
+	conf := loadConfig(t)
+	if p := conf.load().positions["man"]; p.String() != "testdata/read.conf:7" {
+		t.Errorf("Expected man defined at testdata/read.conf:7, but was %v", p)
+	}
+
+	conf.SetOption("man", "x")
+	if _, err := conf.BoolE("man", false); err == nil || strings.Contains(err.Message(), "read.conf") {
+		t.Errorf("Expected position to be dropped after SetOption, but was %v", err)
+	}
+}
+
+func TestLoadParseErrorPosition(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "bad.conf")
+	if err := os.WriteFile(fname, []byte("# bad\nname = tom\nage\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Load(fname)
+	if err == nil || !strings.Contains(err.Message(), fname+":3") {
+		t.Errorf("Expected parse error at line 3, but was %v", err)
+	}
+}
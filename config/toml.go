@@ -0,0 +1,512 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"github.com/roverli/utils/errors"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LoadTOML reads options from the TOML file.
+//
+// The tables, dotted keys and inline tables are flattened into dotted keys,
+// the arrays and the arrays of tables are indexed by "[i]" as LoadJSON, e.g.
+//
+//	[[servers]]
+//	host = "a"
+//
+// is "servers[0].host = a". The strings are unquoted and unescaped, the
+// numbers and date-times keep their literal text, the bools are "true" and
+// "false".
+func LoadTOML(fname string) (*Config, errors.Error) {
+	content, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cann't open file %s", fname)
+	}
+	return migrated(parseTOML(string(content), fname))
+}
+
+// Inner method, parse the TOML content, name is used in the error messages.
+func parseTOML(content string, name string) (*Config, errors.Error) {
+	p := &tomlParser{src: strings.Replace(content, "\r\n", "\n", -1)}
+	root, err := p.parse()
+	if err != nil {
+		return nil, errors.Newf("parse error: %s (%s:%d)", err.Error(), name, p.line())
+	}
+
+	options := make(map[string]string)
+	flatten("", root, options)
+	return newConfig(&snapshot{options: options, positions: make(map[string]position)}), nil
+}
+
+// Inner recursive descent parser of TOML.
+// The tables are map[string]interface{}, the arrays are []interface{},
+// the bools are bool and other values are string.
+type tomlParser struct {
+	src string
+	pos int
+}
+
+// Inner method, return the line number of the current position.
+func (p *tomlParser) line() int {
+	return strings.Count(p.src[:p.pos], "\n") + 1
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *tomlParser) skipSpaces() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+// Inner method, skip the spaces, comments and line breaks.
+func (p *tomlParser) skipBlank() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\n':
+			p.pos++
+		case '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) expect(s string) error {
+	if !strings.HasPrefix(p.src[p.pos:], s) {
+		return fmt.Errorf("expected %q", s)
+	}
+	p.pos += len(s)
+	return nil
+}
+
+// Inner method, skip the rest of the line, only a comment is allowed.
+func (p *tomlParser) endOfLine() error {
+	p.skipSpaces()
+	if p.peek() == '#' {
+		for !p.eof() && p.peek() != '\n' {
+			p.pos++
+		}
+	}
+	if !p.eof() && p.peek() != '\n' {
+		return fmt.Errorf("unexpected %q", p.peek())
+	}
+	return nil
+}
+
+func (p *tomlParser) parse() (map[string]interface{}, error) {
+	root := map[string]interface{}{}
+	current := root
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return root, nil
+		}
+
+		var err error
+		switch {
+		case strings.HasPrefix(p.src[p.pos:], "[["):
+			p.pos += 2
+			current, err = p.header(root, "]]", true)
+		case p.peek() == '[':
+			p.pos++
+			current, err = p.header(root, "]", false)
+		default:
+			err = p.keyValue(current)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err = p.endOfLine(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Inner method, parse the "[table]" or "[[array]]" header and return the table.
+func (p *tomlParser) header(root map[string]interface{}, end string, isArray bool) (map[string]interface{}, error) {
+	p.skipSpaces()
+	path, err := p.key()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if err = p.expect(end); err != nil {
+		return nil, err
+	}
+
+	parent, err := walkTables(root, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+	child, exists := parent[last]
+
+	if isArray {
+		if !exists {
+			child = []interface{}{}
+		}
+		tables, ok := child.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("key %s is not an array of tables", strings.Join(path, "."))
+		}
+		table := map[string]interface{}{}
+		parent[last] = append(tables, table)
+		return table, nil
+	}
+
+	if !exists {
+		table := map[string]interface{}{}
+		parent[last] = table
+		return table, nil
+	}
+	table, ok := child.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("key %s is not a table", strings.Join(path, "."))
+	}
+	return table, nil
+}
+
+// Inner method, walk through the tables of the path, the missing tables are
+// created and the arrays of tables are entered at the last element.
+func walkTables(table map[string]interface{}, path []string) (map[string]interface{}, error) {
+	for i, k := range path {
+		switch child := table[k].(type) {
+		case nil:
+			next := map[string]interface{}{}
+			table[k] = next
+			table = next
+		case map[string]interface{}:
+			table = child
+		case []interface{}:
+			var last map[string]interface{}
+			if len(child) > 0 {
+				last, _ = child[len(child)-1].(map[string]interface{})
+			}
+			if last == nil {
+				return nil, fmt.Errorf("key %s is not a table", strings.Join(path[:i+1], "."))
+			}
+			table = last
+		default:
+			return nil, fmt.Errorf("key %s is not a table", strings.Join(path[:i+1], "."))
+		}
+	}
+	return table, nil
+}
+
+// Inner method, parse the "key = value" into the table.
+func (p *tomlParser) keyValue(table map[string]interface{}) error {
+	path, err := p.key()
+	if err != nil {
+		return err
+	}
+	p.skipSpaces()
+	if err = p.expect("="); err != nil {
+		return err
+	}
+	p.skipSpaces()
+	value, err := p.value()
+	if err != nil {
+		return err
+	}
+
+	parent, err := walkTables(table, path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+	last := path[len(path)-1]
+	if _, exists := parent[last]; exists {
+		return fmt.Errorf("duplicate key %s", strings.Join(path, "."))
+	}
+	parent[last] = value
+	return nil
+}
+
+// Inner method, parse the bare, quoted or dotted key.
+func (p *tomlParser) key() ([]string, error) {
+	path := []string{}
+	for {
+		p.skipSpaces()
+		var part string
+		switch p.peek() {
+		case '"':
+			s, err := p.basicString()
+			if err != nil {
+				return nil, err
+			}
+			part = s
+		case '\'':
+			s, err := p.literalString()
+			if err != nil {
+				return nil, err
+			}
+			part = s
+		default:
+			start := p.pos
+			for !p.eof() && isBareKeyChar(p.peek()) {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, fmt.Errorf("expected key")
+			}
+			part = p.src[start:p.pos]
+		}
+		path = append(path, part)
+
+		p.skipSpaces()
+		if p.peek() != '.' {
+			return path, nil
+		}
+		p.pos++
+	}
+}
+
+func isBareKeyChar(ch byte) bool {
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' || ch == '_' || ch == '-'
+}
+
+func (p *tomlParser) value() (interface{}, error) {
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], `"""`):
+		return p.multilineString(`"""`, true)
+	case strings.HasPrefix(p.src[p.pos:], `'''`):
+		return p.multilineString(`'''`, false)
+	case p.peek() == '"':
+		return p.basicString()
+	case p.peek() == '\'':
+		return p.literalString()
+	case p.peek() == '[':
+		return p.array()
+	case p.peek() == '{':
+		return p.inlineTable()
+	case strings.HasPrefix(p.src[p.pos:], "true"):
+		p.pos += len("true")
+		return true, nil
+	case strings.HasPrefix(p.src[p.pos:], "false"):
+		p.pos += len("false")
+		return false, nil
+	}
+	return p.scalar()
+}
+
+// Inner method, parse the number or date-time, the literal text is kept.
+func (p *tomlParser) scalar() (interface{}, error) {
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune(" \t\n,]}#", rune(p.peek())) {
+		p.pos++
+	}
+	// The date and time may be separated by a space.
+	token := p.src[start:p.pos]
+	if len(token) == 10 && token[4] == '-' && token[7] == '-' &&
+		len(p.src) > p.pos+3 && p.src[p.pos] == ' ' && p.src[p.pos+3] == ':' {
+		p.pos++
+		for !p.eof() && !strings.ContainsRune(" \t\n,]}#", rune(p.peek())) {
+			p.pos++
+		}
+		token = p.src[start:p.pos]
+	}
+
+	if token == "" {
+		return nil, fmt.Errorf("expected value")
+	}
+	if _, err := parseInt(token, 64); err == nil {
+		return token, nil
+	}
+	switch strings.TrimLeft(token, "+-") {
+	case "inf", "nan":
+		return token, nil
+	}
+	if _, err := strconv.ParseFloat(strings.Replace(token, "_", "", -1), 64); err == nil && validUnderscores(token) {
+		return token, nil
+	}
+	if token[0] >= '0' && token[0] <= '9' && strings.ContainsAny(token, "-:") {
+		return token, nil
+	}
+	p.pos = start
+	return nil, fmt.Errorf("invalid value %q", token)
+}
+
+func (p *tomlParser) array() (interface{}, error) {
+	p.pos++
+	values := []interface{}{}
+	for {
+		p.skipBlank()
+		if p.peek() == ']' {
+			p.pos++
+			return values, nil
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+
+		p.skipBlank()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, fmt.Errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+func (p *tomlParser) inlineTable() (interface{}, error) {
+	p.pos++
+	table := map[string]interface{}{}
+	p.skipSpaces()
+	if p.peek() == '}' {
+		p.pos++
+		return table, nil
+	}
+	for {
+		p.skipSpaces()
+		if err := p.keyValue(table); err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return table, nil
+		default:
+			return nil, fmt.Errorf("expected ',' or '}' in inline table")
+		}
+	}
+}
+
+func (p *tomlParser) literalString() (string, error) {
+	p.pos++
+	end := strings.IndexAny(p.src[p.pos:], "'\n")
+	if end < 0 || p.src[p.pos+end] != '\'' {
+		return "", fmt.Errorf("unterminated string")
+	}
+	s := p.src[p.pos : p.pos+end]
+	p.pos += end + 1
+	return s, nil
+}
+
+func (p *tomlParser) basicString() (string, error) {
+	p.pos++
+	var b strings.Builder
+	for !p.eof() {
+		ch := p.peek()
+		switch ch {
+		case '"':
+			p.pos++
+			return b.String(), nil
+		case '\n':
+			return "", fmt.Errorf("unterminated string")
+		case '\\':
+			if err := p.escape(&b); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(ch)
+			p.pos++
+		}
+	}
+	return "", fmt.Errorf("unterminated string")
+}
+
+// Inner method, parse the multi-line basic or literal string quoted by quote.
+func (p *tomlParser) multilineString(quote string, basic bool) (string, error) {
+	p.pos += len(quote)
+	// A line break right after the opening quotes is trimmed.
+	if p.peek() == '\n' {
+		p.pos++
+	}
+
+	var b strings.Builder
+	for !p.eof() {
+		if strings.HasPrefix(p.src[p.pos:], quote) {
+			p.pos += len(quote)
+			// Up to two quotes are allowed right before the closing quotes.
+			for i := 0; i < 2 && p.peek() == quote[0]; i++ {
+				b.WriteByte(quote[0])
+				p.pos++
+			}
+			return b.String(), nil
+		}
+
+		ch := p.peek()
+		if basic && ch == '\\' {
+			// A line ending backslash trims the following spaces and line breaks.
+			rest := strings.TrimLeft(p.src[p.pos+1:], " \t")
+			if strings.HasPrefix(rest, "\n") {
+				p.pos = len(p.src) - len(strings.TrimLeft(rest, " \t\n"))
+				continue
+			}
+			if err := p.escape(&b); err != nil {
+				return "", err
+			}
+			continue
+		}
+		b.WriteByte(ch)
+		p.pos++
+	}
+	return "", fmt.Errorf("unterminated string")
+}
+
+// Inner method, write the escape sequence at the position to b.
+func (p *tomlParser) escape(b *strings.Builder) error {
+	if p.pos+1 >= len(p.src) {
+		return fmt.Errorf("unterminated string")
+	}
+	ch := p.src[p.pos+1]
+	p.pos += 2
+	switch ch {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case 'e':
+		b.WriteByte(0x1b)
+	case '"', '\\':
+		b.WriteByte(ch)
+	case 'u', 'U':
+		size := 4
+		if ch == 'U' {
+			size = 8
+		}
+		if p.pos+size > len(p.src) {
+			return fmt.Errorf("malformed unicode escape")
+		}
+		v, err := strconv.ParseUint(p.src[p.pos:p.pos+size], 16, 32)
+		if err != nil {
+			return fmt.Errorf("malformed unicode escape")
+		}
+		b.WriteRune(rune(v))
+		p.pos += size
+	default:
+		return fmt.Errorf("invalid escape \\%c", ch)
+	}
+	return nil
+}
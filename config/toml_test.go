@@ -0,0 +1,86 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadTOML(t *testing.T) {
+	conf, err := LoadTOML("testdata/app.toml")
+	if err != nil {
+		t.Fatalf("Expected load app.toml no error, but was %v", err.Message())
+	}
+
+	cases := map[string]string{
+		"name":               "app",
+		"debug":              "true",
+		"title":              `C:\apps\app`,
+		"quoted key":         "q",
+		"site.url":           "http://example.com",
+		"db.host":            "db.local",
+		"db.port":            "3_306",
+		"db.ratio":           "0.75",
+		"db.slaves[0]":       "s1",
+		"db.slaves[1]":       "s2",
+		"db.started":         "1979-05-27 07:32:00Z",
+		"db.limits.max":      "10",
+		"db.limits.idle.min": "2",
+		"servers[0].host":    "a",
+		"servers[0].port":    "8001",
+		"servers[1].host":    "b",
+		"servers[1].port":    "8002",
+		"text.escaped":       "tab\there 你",
+		"text.multi":         "line1\nline2 joined",
+		"text.literal":       `raw \n`,
+	}
+	for k, expected := range cases {
+		if v, ok := conf.load().lookup(k); !ok || v != expected {
+			t.Errorf("Expected %q to be %q, but was %q", k, expected, v)
+		}
+	}
+	if len(conf.Keys()) != len(cases) {
+		t.Errorf("Expected %d keys, but was %q", len(cases), conf.Keys())
+	}
+
+	if v := conf.Int("db.port", 0); v != 3306 {
+		t.Errorf("Expected db.port to be 3306, but was %v", v)
+	}
+
+	// The date-time separated by a space is a time.
+	expected := time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC)
+	if v, err := conf.TimeE("db.started", time.Time{}); err != nil || !v.Equal(expected) {
+		t.Errorf("Expected db.started to be %v, but was %v, %v", expected, v, err)
+	}
+	var s struct {
+		Started time.Time `conf:"db.started"`
+	}
+	if err := Unmarshal(conf, &s); err != nil || !s.Started.Equal(expected) {
+		t.Errorf("Expected db.started bound to %v, but was %v, %v", expected, s.Started, err)
+	}
+}
+
+func TestParseTOMLError(t *testing.T) {
+	cases := map[string]string{
+		"a = 1\nb = \n":         "bad.toml:2",
+		"a = 1\na = 2\n":        "duplicate key a",
+		"a = 1\n[a]\n":          "key a is not a table",
+		"s = \"abc\n":           "unterminated string",
+		"x = 1 2\n":             "unexpected",
+		"[t]\nv = [1, 2\n":      "bad.toml:3",
+		"[t]\nv = wrong\n":      "invalid value",
+		"s = \"\\q\"\n":         "invalid escape",
+		"a.b = 1\n[[a.b]]\n":    "not an array of tables",
+		"t = { a = 1 b = 2 }\n": "inline table",
+	}
+	for content, expected := range cases {
+		_, err := parseTOML(content, "bad.toml")
+		if err == nil || !strings.Contains(err.Message(), expected) {
+			t.Errorf("Expected parse %q error contains %q, but was %v", content, expected, err)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"github.com/roverli/utils/errors"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Watcher reloads a config file when it changes.
+//
+// The file is polled by its modify time and size every interval. A changed
+// file is re-parsed and the new config replaces the current one atomically,
+// then the OnChange callbacks are invoked in the registration order.
+// If the file fails to parse, the last good config is kept and the
+// OnError callbacks are invoked.
+//
+// The callbacks are serialized: the notifications of the watch loop and of
+// Reload are invoked one at a time, in the order of the reloads, and never
+// after Close returns.
+//
+// The config returned by Watcher.Config is replaced as a whole on reload,
+// the changes made on it by SetOption etc. are lost after the next reload.
+type Watcher struct {
+	fname    string
+	interval time.Duration
+	current  atomic.Value // *Config
+
+	mu       sync.Mutex
+	modTime  time.Time
+	size     int64
+	onChange []func(old, new *Config, changedKeys []string)
+	onError  []func(err errors.Error)
+
+	stop chan struct{}
+	done chan struct{}
+
+	// The notifications to invoke in order, guarded by mu.
+	queue []func()
+
+	// Held while invoking the notifications, owner is the goroutine which
+	// holds it, so that a callback calling Reload or Close doesn't wait for
+	// itself.
+	notifyMu sync.Mutex
+	owner    atomic.Uint64
+}
+
+// Watch loads the file and starts to watch it for changes.
+// It returns error if the first load failed.
+func Watch(fname string, interval time.Duration) (*Watcher, errors.Error) {
+	if interval <= 0 {
+		return nil, errors.Newf("config: watch interval must be positive, but was %v", interval)
+	}
+
+	w := &Watcher{
+		fname:    fname,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	info, err := os.Stat(fname)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cann't stat file %s", fname)
+	}
+	conf, e := Load(fname)
+	if e != nil {
+		return nil, e
+	}
+	w.modTime, w.size = info.ModTime(), info.Size()
+	w.current.Store(conf)
+
+	go w.loop()
+	return w, nil
+}
+
+// Config returns the current config snapshot.
+func (w *Watcher) Config() *Config {
+	return w.current.Load().(*Config)
+}
+
+// OnChange registers a callback invoked after each reload that changed options.
+// changedKeys is the sorted list of added, removed and modified keys.
+// The callbacks are invoked without holding the lock of w, so they may call
+// the methods of w. The notification of a Reload from a callback is invoked
+// after the current one, a Close from a callback doesn't wait for it.
+func (w *Watcher) OnChange(f func(old, new *Config, changedKeys []string)) {
+	w.mu.Lock()
+	w.onChange = append(w.onChange, f)
+	w.mu.Unlock()
+}
+
+// OnError registers a callback invoked when the file cann't be reloaded.
+func (w *Watcher) OnError(f func(err errors.Error)) {
+	w.mu.Lock()
+	w.onError = append(w.onError, f)
+	w.mu.Unlock()
+}
+
+// Reload re-parses the file immediately, whether it changed or not.
+// On error, the current config is kept.
+func (w *Watcher) Reload() errors.Error {
+	w.mu.Lock()
+	err := w.reload()
+	w.mu.Unlock()
+
+	w.notify()
+	return err
+}
+
+// Close stops watching the file and waits for the running callbacks. The
+// current config is still available.
+func (w *Watcher) Close() {
+	w.mu.Lock()
+	select {
+	case <-w.stop:
+	default:
+		close(w.stop)
+	}
+	w.mu.Unlock()
+
+	// Called from a callback, the loop may be invoking it, don't wait.
+	if w.owner.Load() == goid() {
+		return
+	}
+	<-w.done
+	w.notifyMu.Lock()
+	w.notifyMu.Unlock()
+}
+
+func (w *Watcher) loop() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.poll()
+			w.notify()
+		}
+	}
+}
+
+// Inner method, reload the file if its modify time or size changed.
+func (w *Watcher) poll() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	info, err := os.Stat(w.fname)
+	if err != nil {
+		w.fail(errors.Wrapf(err, "cann't stat file %s", w.fname))
+		return
+	}
+	if info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return
+	}
+	w.modTime, w.size = info.ModTime(), info.Size()
+	w.reload()
+}
+
+// Inner method, must be called with w.mu held. The callbacks of the reload
+// are queued, notify invokes them after w.mu is released, so that the
+// callbacks can call the methods of w.
+func (w *Watcher) reload() errors.Error {
+	conf, err := Load(w.fname)
+	if err != nil {
+		w.fail(err)
+		return err
+	}
+
+	old := w.Config()
+	keys := Diff(old, conf).Keys()
+	if len(keys) == 0 {
+		return nil
+	}
+
+	w.current.Store(conf)
+	callbacks := append([]func(old, new *Config, changedKeys []string){}, w.onChange...)
+	w.queue = append(w.queue, func() {
+		for _, f := range callbacks {
+			f(old, conf, keys)
+		}
+	})
+	return nil
+}
+
+// Inner method, must be called with w.mu held. Queue the error callbacks,
+// see reload.
+func (w *Watcher) fail(err errors.Error) {
+	callbacks := append([]func(err errors.Error){}, w.onError...)
+	w.queue = append(w.queue, func() {
+		for _, f := range callbacks {
+			f(err)
+		}
+	})
+}
+
+// Inner method, invoke the queued notifications in order. A call from a
+// callback returns at once, the notifications it queued are invoked by the
+// outer call. The notifications queued after Close are dropped.
+func (w *Watcher) notify() {
+	id := goid()
+	if w.owner.Load() == id {
+		return
+	}
+	w.notifyMu.Lock()
+	defer w.notifyMu.Unlock()
+	w.owner.Store(id)
+	defer w.owner.Store(0)
+
+	for {
+		w.mu.Lock()
+		select {
+		case <-w.stop:
+			w.queue = nil
+		default:
+		}
+		if len(w.queue) == 0 {
+			w.mu.Unlock()
+			return
+		}
+		f := w.queue[0]
+		w.queue = w.queue[1:]
+		w.mu.Unlock()
+		f()
+	}
+}
+
+// Inner method, return the id of the current goroutine, it's parsed from the
+// "goroutine 1 [running]:" header of the stack.
+func goid() uint64 {
+	var buf [64]byte
+	header := string(buf[:runtime.Stack(buf[:], false)])
+	header = strings.TrimPrefix(header, "goroutine ")
+	if i := strings.IndexByte(header, ' '); i > 0 {
+		header = header[:i]
+	}
+	id, _ := strconv.ParseUint(header, 10, 64)
+	return id
+}
@@ -0,0 +1,203 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"github.com/roverli/utils/errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Write the file content and push its modify time forward,
+// so that the change is visible even on coarse-grained file systems.
+func writeConf(t *testing.T, fname, content string, mtime time.Time) {
+	if err := os.WriteFile(fname, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(fname, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "app.conf")
+	now := time.Now()
+	writeConf(t, fname, "level = info\nlimit = 10\n", now)
+
+	w, err := Watch(fname, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Expected watch no error, but was %v", err.Message())
+	}
+	defer w.Close()
+
+	changes := make(chan []string, 1)
+	w.OnChange(func(old, new *Config, keys []string) {
+		if old.String("level", "") != "info" || new.String("level", "") != "debug" {
+			t.Errorf("Unexpected old and new config %v %v", old.Keys(), new.Keys())
+		}
+		changes <- keys
+	})
+	errs := make(chan errors.Error, 1)
+	w.OnError(func(err errors.Error) {
+		errs <- err
+	})
+
+	writeConf(t, fname, "level = debug\nlimit = 10\nsize = 1\n", now.Add(time.Second))
+	select {
+	case keys := <-changes:
+		if len(keys) != 2 || keys[0] != "level" || keys[1] != "size" {
+			t.Errorf(`Expected changed keys to be ["level" "size"], but was %q`, keys)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected change callback to be invoked.")
+	}
+	if v := w.Config().String("level", ""); v != "debug" {
+		t.Errorf(`Expected level to be "debug", but was %v`, v)
+	}
+
+	writeConf(t, fname, "level\n", now.Add(2*time.Second))
+	select {
+	case <-errs:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected error callback to be invoked.")
+	}
+	if v := w.Config().String("level", ""); v != "debug" {
+		t.Errorf(`Expected last good level "debug" to be kept, but was %v`, v)
+	}
+}
+
+func TestWatchBadFile(t *testing.T) {
+	if _, err := Watch("testdata/not_exist.conf", time.Second); err == nil {
+		t.Error("Expected watch on missing file to fail.")
+	}
+	if _, err := Watch("testdata/read.conf", 0); err == nil {
+		t.Error("Expected watch with zero interval to fail.")
+	}
+}
+
+func TestWatchReentrantCallbacks(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "app.conf")
+	now := time.Now()
+	writeConf(t, fname, "level = info\n", now)
+	w, err := Watch(fname, time.Hour)
+	if err != nil {
+		t.Fatalf("Expected watch no error, but was %v", err.Message())
+	}
+	defer w.Close()
+
+	// The callbacks of Reload call the methods of w.
+	reloaded := false
+	w.OnChange(func(old, new *Config, keys []string) {
+		if !reloaded {
+			reloaded = true
+			w.OnError(func(err errors.Error) {})
+			w.Reload()
+		}
+	})
+	writeConf(t, fname, "level = debug\n", now)
+	done := make(chan errors.Error)
+	go func() {
+		done <- w.Reload()
+	}()
+	select {
+	case err := <-done:
+		if err != nil || !reloaded {
+			t.Errorf("Expected reload in callback, but was %v %v", err, reloaded)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected Reload from a callback not to deadlock.")
+	}
+
+	// The callbacks of the watch loop close w.
+	w, err = Watch(fname, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Expected watch no error, but was %v", err.Message())
+	}
+	closed := make(chan bool)
+	w.OnChange(func(old, new *Config, keys []string) {
+		w.Close()
+		close(closed)
+	})
+	writeConf(t, fname, "level = warn\n", now.Add(time.Second))
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected Close from a callback not to deadlock.")
+	}
+	w.Close()
+}
+
+func TestWatchSerializedCallbacks(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "app.conf")
+	now := time.Now()
+	writeConf(t, fname, "n = 0\n", now)
+	w, err := Watch(fname, time.Millisecond)
+	if err != nil {
+		t.Fatalf("Expected watch no error, but was %v", err.Message())
+	}
+
+	// The loop and Reload notify concurrently, the callbacks never overlap
+	// and see the reloads in order, the old config is the previous new one.
+	var running atomic.Int32
+	var mu sync.Mutex
+	last := w.Config()
+	w.OnChange(func(old, new *Config, keys []string) {
+		if running.Add(1) != 1 {
+			t.Errorf("Expected the callbacks serialized")
+		}
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		if old != last {
+			t.Errorf("Expected the reloads in order")
+		}
+		last = new
+		mu.Unlock()
+		running.Add(-1)
+	})
+	var wg sync.WaitGroup
+	for i := 1; i <= 20; i++ {
+		writeConf(t, fname, fmt.Sprintf("n = %d\n", i), now.Add(time.Duration(i)*time.Second))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.Reload()
+		}()
+	}
+	wg.Wait()
+	w.Close()
+}
+
+func TestWatchCloseWaitsCallbacks(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "app.conf")
+	now := time.Now()
+	writeConf(t, fname, "level = info\n", now)
+	w, err := Watch(fname, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Expected watch no error, but was %v", err.Message())
+	}
+
+	started := make(chan bool)
+	var finished atomic.Bool
+	w.OnChange(func(old, new *Config, keys []string) {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		finished.Store(true)
+	})
+	writeConf(t, fname, "level = debug\n", now.Add(time.Second))
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the change notified.")
+	}
+	w.Close()
+	if !finished.Load() {
+		t.Errorf("Expected Close to wait for the running callback")
+	}
+}
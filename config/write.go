@@ -0,0 +1,278 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bufio"
+	"github.com/roverli/utils/errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Inner representation of a line in the loaded file.
+type line struct {
+	// The original text without the line break.
+	raw string
+
+	// The section name if the line is a "[section]" header.
+	section string
+
+	// The full key and the value if the line is an option.
+	key   string
+	value string
+
+	// The option is defined in an included file, the line is not written.
+	included bool
+
+	// The profile of the option, empty for the base options.
+	profile string
+
+	// The list of a "list[] = value" line.
+	list string
+}
+
+// Inner representation of a "[section]" and its lines when writing.
+// The first block holds the lines before any header and has no section.
+type block struct {
+	section string
+	lines   []string
+}
+
+// WriteTo writes the config in the file format to w.
+//
+// The layout of the loaded file is kept: comments, blank lines, sections and
+// the order of the keys. Modified options are rewritten in place and removed
+// options are dropped. New options are written in key order at the end of
+// their section, or at the end of the lines before the first section header.
+// The included files are not written, the include lines are kept and the
+// modified included options are written as new options.
+//
+// The value of a key is written to the line which supplied it, e.g. the
+// "key@prod" line when the "prod" profile is active. The lines of the
+// inactive profiles and the base lines overridden by a profile are kept.
+//
+// It returns error and writes nothing if an option cann't be read back as it
+// is, e.g. the key contains "=", "@" or a line break, the key is a comment or
+// an include directive, or the value has a line break or leading spaces.
+func (c *Config) WriteTo(w io.Writer) (int64, error) {
+	s := c.load()
+	blocks := []*block{{}}
+	written := make(map[string]bool)
+	replaced := c.replacedLists()
+	owners := c.owners(replaced)
+
+	for i, l := range c.lines {
+		if o, ok := owners[l.key]; l.key != "" && (!ok || o != i) {
+			// The removed keys are dropped, but the inactive profiles and
+			// the base lists replaced by a profile are kept.
+			_, exists := s.options[l.key]
+			if l.included || (!exists && c.isActive(l.profile) && !(l.profile == "" && replaced[l.list])) {
+				continue
+			}
+			b := blocks[len(blocks)-1]
+			b.lines = append(b.lines, l.raw)
+			continue
+		}
+
+		switch {
+		case l.section != "":
+			blocks = append(blocks, &block{section: l.section, lines: []string{l.raw}})
+			continue
+		case l.included:
+			// Keep the included value, a modified one is written as a new option.
+			if v, ok := s.options[l.key]; ok && v == l.value {
+				written[l.key] = true
+			}
+			continue
+		case l.key != "":
+			v, ok := s.options[l.key]
+			if !ok {
+				continue
+			}
+			written[l.key] = true
+			if v != l.value {
+				l.raw = replaceValue(l.raw, v)
+				if read, ok := readBack(l.raw); !ok || read.value != v {
+					return 0, unwritable(l.key)
+				}
+			}
+		}
+		b := blocks[len(blocks)-1]
+		b.lines = append(b.lines, l.raw)
+	}
+
+	keys := []string{}
+	for k := range s.options {
+		if !written[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b := findBlock(blocks, k)
+		local := k
+		if b.section != "" {
+			local = k[len(b.section)+1:]
+		}
+		text := local + " = " + s.options[k]
+		if read, ok := readBack(text); !ok || read.key != local || read.profile != "" || read.value != s.options[k] {
+			return 0, unwritable(k)
+		}
+		b.insert(text)
+	}
+
+	bw := bufio.NewWriter(w)
+	var n int64
+	for _, b := range blocks {
+		for _, l := range b.lines {
+			m, err := bw.WriteString(l + "\n")
+			n += int64(m)
+			if err != nil {
+				return n, errors.Wrap(err, "write config error.")
+			}
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return n, errors.Wrap(err, "write config error.")
+	}
+	return n, nil
+}
+
+// Inner method, parse the written line as the parser reads it back. The
+// include directive is not parsed, it's never an option.
+func readBack(text string) (line, bool) {
+	if isInclude(strings.TrimSpace(text)) {
+		return line{}, false
+	}
+	lines, err := newParser(nil).parseReader(strings.NewReader(text), "")
+	if err != nil || len(lines) != 1 {
+		return line{}, false
+	}
+	return lines[0], true
+}
+
+// Inner method, the error of an option which cann't be read back. The value
+// is not in the message, it may be a secret.
+func unwritable(key string) errors.Error {
+	return errors.Newf("config: key %q cann't be written, its line is not read back as it is", key)
+}
+
+// Inner method, return the index of the line which supplied the value of
+// each key: the last line of the last active profile which has the key,
+// or else the last base line of the key which is not in a replaced list.
+func (c *Config) owners(replaced map[string]bool) map[string]int {
+	owners := make(map[string]int)
+	ranks := make(map[string]int)
+	for i, l := range c.lines {
+		if l.key == "" || !c.isActive(l.profile) || (l.profile == "" && replaced[l.list]) {
+			continue
+		}
+		rank := 0
+		for j, p := range c.profiles {
+			if p == l.profile {
+				rank = j + 1
+			}
+		}
+		if r, ok := ranks[l.key]; !ok || rank >= r {
+			owners[l.key], ranks[l.key] = i, rank
+		}
+	}
+	return owners
+}
+
+// Inner method, return the lists of the "list[]" lines in the active profiles,
+// they replace the base lists.
+func (c *Config) replacedLists() map[string]bool {
+	replaced := make(map[string]bool)
+	for _, l := range c.lines {
+		if l.list != "" && l.profile != "" && c.isActive(l.profile) {
+			replaced[l.list] = true
+		}
+	}
+	return replaced
+}
+
+// Save writes the config to the file.
+//
+// The content is written to a temporary file in the same directory first,
+// and then renamed to fname, so that a crash never leaves a truncated file.
+// The permission of an existing file is kept. If fname is a symlink, the file
+// it links to is replaced and the symlink is kept.
+func (c *Config) Save(fname string) errors.Error {
+	target := fname
+	if resolved, err := filepath.EvalSymlinks(fname); err == nil {
+		target = resolved
+	}
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(target); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".tmp")
+	if err != nil {
+		return errors.Wrapf(err, "cann't create temp file for %s", fname)
+	}
+	tmpName := tmp.Name()
+
+	fail := func(err error, msg string) errors.Error {
+		tmp.Close()
+		os.Remove(tmpName)
+		return errors.Wrapf(err, "%s %s", msg, fname)
+	}
+
+	if _, err = c.WriteTo(tmp); err != nil {
+		return fail(err, "write file")
+	}
+	if err = tmp.Chmod(perm); err != nil {
+		return fail(err, "chmod file")
+	}
+	if err = tmp.Sync(); err != nil {
+		return fail(err, "sync file")
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return errors.Wrapf(err, "close file %s error.", tmpName)
+	}
+	if err = os.Rename(tmpName, target); err != nil {
+		os.Remove(tmpName)
+		return errors.Wrapf(err, "cann't rename %s to %s", tmpName, target)
+	}
+	return nil
+}
+
+// Inner method, replace the value in the "key = value" line and keep the
+// indent and spaces around "=".
+func replaceValue(raw string, value string) string {
+	i := strings.Index(raw, "=")
+	rest := raw[i+1:]
+	space := rest[:len(rest)-len(strings.TrimLeft(rest, " \t"))]
+	return raw[:i+1] + space + value
+}
+
+// Inner method, find the block with the longest section that the key belongs to.
+// The last block wins if the section is declared more than once.
+func findBlock(blocks []*block, key string) *block {
+	found := blocks[0]
+	for _, b := range blocks[1:] {
+		if strings.HasPrefix(key, b.section+".") && len(b.section) >= len(found.section) {
+			found = b
+		}
+	}
+	return found
+}
+
+// Inner method, insert the line after the last non-blank line of the block.
+func (b *block) insert(l string) {
+	i := len(b.lines)
+	for i > 0 && strings.TrimSpace(b.lines[i-1]) == "" {
+		i--
+	}
+	b.lines = append(b.lines, "")
+	copy(b.lines[i+1:], b.lines[i:])
+	b.lines[i] = l
+}
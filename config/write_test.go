@@ -0,0 +1,191 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteToRoundTrip(t *testing.T) {
+	for _, fname := range []string{"testdata/read.conf", "testdata/section.conf"} {
+		conf, err := Load(fname)
+		if err != nil {
+			t.Fatalf("Expected load %s no error, but was %v", fname, err.Message())
+		}
+
+		var buf bytes.Buffer
+		if _, err := conf.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+
+		content, _ := os.ReadFile(fname)
+		if buf.String() != string(content) {
+			t.Errorf("Expected %s to round trip, but was:\n%s", fname, buf.String())
+		}
+	}
+}
+
+func TestWriteToEdit(t *testing.T) {
+	conf, err := Load("testdata/section.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf.SetOption("name", "web")
+	conf.SetOption("version", "2")
+	conf.ClearOption("database.port")
+	conf.SetOption("database.user", "root")
+	conf.SetOption("cache.redis.db", "0")
+
+	var buf bytes.Buffer
+	if _, err := conf.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# Global options
+name = web
+version = 2
+
+[database]
+host = db.local
+user = root
+
+[ cache.redis ]
+addr = 127.0.0.1:6379
+db = 0
+`
+	if buf.String() != expected {
+		t.Errorf("Expected written config to be:\n%s\nbut was:\n%s", expected, buf.String())
+	}
+}
+
+func TestWriteToNew(t *testing.T) {
+	conf := New()
+	conf.SetOption("name", "tom")
+	conf.SetOption("age", "25")
+
+	var buf bytes.Buffer
+	if _, err := conf.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "age = 25\nname = tom\n" {
+		t.Errorf("Expected keys to be written in order, but was:\n%s", buf.String())
+	}
+}
+
+func TestWriteToInvalid(t *testing.T) {
+	cases := map[string]string{
+		"a = b":     "x",
+		"a\nb":      "x",
+		"a":         "x\nb = injected",
+		"windows":   "x\r\nb = injected",
+		"#a":        "x",
+		"u@prod":    "x",
+		"[x]":       "x",
+		"include x": "v",
+		"list[]":    "x",
+		" b":        "x",
+		"leading":   " x",
+		"trailing":  "x ",
+	}
+	for k, v := range cases {
+		conf := New()
+		conf.SetOption(k, v)
+		var buf bytes.Buffer
+		if n, err := conf.WriteTo(&buf); err == nil || n != 0 || buf.Len() != 0 {
+			t.Errorf("Expected error of %q = %q, but was %v:\n%s", k, v, err, buf.String())
+		}
+	}
+
+	// The key is written without the section prefix in the section.
+	conf, err := LoadReader(strings.NewReader("[db]\nhost = localhost\n"), "app.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.SetOption("db.#port", "3306")
+	if _, err := conf.WriteTo(&bytes.Buffer{}); err == nil {
+		t.Errorf("Expected error of db.#port, but was nil")
+	}
+
+	fname := filepath.Join(t.TempDir(), "app.conf")
+	if err := os.WriteFile(fname, []byte("a = x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	conf, err = Load(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.SetOption("a", "y\nb = injected")
+	if err := conf.Save(fname); err == nil {
+		t.Errorf("Expected save error, but was nil")
+	}
+	if content, _ := os.ReadFile(fname); string(content) != "a = x\n" {
+		t.Errorf("Expected file not modified, but was:\n%s", content)
+	}
+}
+
+func TestSave(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "app.conf")
+	if err := os.WriteFile(fname, []byte("# app\nname=tom\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	conf, err := Load(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.SetOption("name", "li")
+	if err := conf.Save(fname); err != nil {
+		t.Fatalf("Expected save no error, but was %v", err.Message())
+	}
+
+	content, _ := os.ReadFile(fname)
+	if string(content) != "# app\nname=li\n" {
+		t.Errorf("Unexpected saved content:\n%s", content)
+	}
+	if info, _ := os.Stat(fname); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected file permission to be kept, but was %v", info.Mode().Perm())
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(fname))
+	if len(entries) != 1 {
+		t.Errorf("Expected temp file to be removed, but found %d files", len(entries))
+	}
+}
+
+func TestSaveSymlink(t *testing.T) {
+	dir := t.TempDir()
+	resolved := filepath.Join(dir, "data", "app.conf")
+	if err := os.MkdirAll(filepath.Dir(resolved), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(resolved, []byte("name=tom\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	fname := filepath.Join(dir, "app.conf")
+	if err := os.Symlink(filepath.Join("data", "app.conf"), fname); err != nil {
+		t.Skipf("symlink not supported: %v", err)
+	}
+
+	conf, err := Load(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.SetOption("name", "li")
+	if err := conf.Save(fname); err != nil {
+		t.Fatalf("Expected save no error, but was %v", err.Message())
+	}
+
+	if info, err := os.Lstat(fname); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected symlink to be kept, but was %v", err)
+	}
+	if content, _ := os.ReadFile(resolved); string(content) != "name=li\n" {
+		t.Errorf("Expected linked file to be saved, but was:\n%s", content)
+	}
+}
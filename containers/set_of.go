@@ -0,0 +1,212 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package containers
+
+// Create a new type safe set with elements.
+func NewSetOf[T comparable](elements ...T) *SetOf[T] {
+	s := &SetOf[T]{make(map[T]struct{}, len(elements))}
+	for _, e := range elements {
+		s.elements[e] = struct{}{}
+	}
+	return s
+}
+
+// SetOf is the type safe form of Set, the elements are of type T, so the
+// callbacks need no type assertion and an int64 is never mixed with an int.
+// The methods of Set modify this set as Set does, the Unioned, Intersected,
+// Subtracted, With and Without methods return a new set and keep this set.
+// SetOf is not thread safe.
+type SetOf[T comparable] struct {
+	elements map[T]struct{}
+}
+
+// Returns the number of elements in this set (its cardinality).
+func (s *SetOf[T]) Size() int {
+	return len(s.elements)
+}
+
+// Returns true if this set contains no elements.
+func (s *SetOf[T]) IsEmpty() bool {
+	return len(s.elements) == 0
+}
+
+// Returns true if this set contains the specified element.
+func (s *SetOf[T]) Contains(v T) bool {
+	_, ok := s.elements[v]
+	return ok
+}
+
+// Returns an slice containing all of the elements in this set.
+// The caller is free to modify the returned array.
+func (s *SetOf[T]) ToSlice() []T {
+	values := make([]T, 0, len(s.elements))
+	for k := range s.elements {
+		values = append(values, k)
+	}
+	return values
+}
+
+// Adds the specified element to this set
+// Return true, if this set already contain the specified element
+func (s *SetOf[T]) Add(v T) bool {
+	_, ok := s.elements[v]
+	s.elements[v] = struct{}{}
+	return ok
+}
+
+// Removes the specified element from this set
+// Return true, if this set contained the specified element
+func (s *SetOf[T]) Remove(v T) bool {
+	_, ok := s.elements[v]
+	delete(s.elements, v)
+	return ok
+}
+
+// Removes all of the elements from this set.
+func (s *SetOf[T]) Clear() {
+	s.elements = make(map[T]struct{})
+}
+
+// Adds all elements in s1 into this set.
+func (s *SetOf[T]) Union(s1 *SetOf[T]) {
+	if s1 == nil {
+		return
+	}
+	for k := range s1.elements {
+		s.elements[k] = struct{}{}
+	}
+}
+
+// Removes all elements not in s1 from this set.
+func (s *SetOf[T]) Intersect(s1 *SetOf[T]) {
+	if s1 == nil {
+		return
+	}
+	for k := range s.elements {
+		if !s1.Contains(k) {
+			delete(s.elements, k)
+		}
+	}
+}
+
+// Removes all elements in s1 from this set.
+func (s *SetOf[T]) Subtract(s1 *SetOf[T]) {
+	if s1 == nil {
+		return
+	}
+	for k := range s1.elements {
+		delete(s.elements, k)
+	}
+}
+
+// Returns a new set with the elements in this set or in s1.
+func (s *SetOf[T]) Unioned(s1 *SetOf[T]) *SetOf[T] {
+	result := s.Clone()
+	result.Union(s1)
+	return result
+}
+
+// Returns a new set with the elements in both this set and s1.
+func (s *SetOf[T]) Intersected(s1 *SetOf[T]) *SetOf[T] {
+	if s1 == nil {
+		return s.Clone()
+	}
+	result := NewSetOf[T]()
+	for k := range s.elements {
+		if s1.Contains(k) {
+			result.elements[k] = struct{}{}
+		}
+	}
+	return result
+}
+
+// Returns a new set with the elements in this set but not in s1.
+func (s *SetOf[T]) Subtracted(s1 *SetOf[T]) *SetOf[T] {
+	result := NewSetOf[T]()
+	for k := range s.elements {
+		if s1 == nil || !s1.Contains(k) {
+			result.elements[k] = struct{}{}
+		}
+	}
+	return result
+}
+
+// Returns a new set with the elements in this set and the specified elements.
+func (s *SetOf[T]) With(elements ...T) *SetOf[T] {
+	result := s.Clone()
+	for _, e := range elements {
+		result.elements[e] = struct{}{}
+	}
+	return result
+}
+
+// Returns a new set with the elements in this set except the specified elements.
+func (s *SetOf[T]) Without(elements ...T) *SetOf[T] {
+	result := s.Clone()
+	for _, e := range elements {
+		delete(result.elements, e)
+	}
+	return result
+}
+
+// Returns true when all elements in this set are in s1.
+func (s *SetOf[T]) IsSubset(s1 *SetOf[T]) bool {
+	if s1 == nil || s.Size() > s1.Size() {
+		return false
+	}
+	for k := range s.elements {
+		if !s1.Contains(k) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns true when two sets has the same elements.
+func (s *SetOf[T]) IsEqual(s1 *SetOf[T]) bool {
+	return s1 != nil && s.Size() == s1.Size() && s.IsSubset(s1)
+}
+
+// Create a new set, and copy all the elements in this set.
+func (s *SetOf[T]) Clone() *SetOf[T] {
+	elements := make(map[T]struct{}, len(s.elements))
+	for k := range s.elements {
+		elements[k] = struct{}{}
+	}
+	return &SetOf[T]{elements}
+}
+
+// Iterate the set elements and invoke f by every element.
+func (s *SetOf[T]) Foreach(f func(T)) {
+	for k := range s.elements {
+		f(k)
+	}
+}
+
+// Create a new set, mapping the elements by call f.
+// Use MapSetOf to map the elements to another type.
+func (s *SetOf[T]) Map(f func(T) T) *SetOf[T] {
+	return MapSetOf(s, f)
+}
+
+// Create a new set with all elements satisfied f.
+func (s *SetOf[T]) Filter(f func(T) bool) *SetOf[T] {
+	result := NewSetOf[T]()
+	for k := range s.elements {
+		if f(k) {
+			result.elements[k] = struct{}{}
+		}
+	}
+	return result
+}
+
+// Create a new set of type U, mapping the elements of s by call f.
+func MapSetOf[T, U comparable](s *SetOf[T], f func(T) U) *SetOf[U] {
+	result := &SetOf[U]{make(map[U]struct{}, s.Size())}
+	for k := range s.elements {
+		result.elements[f(k)] = struct{}{}
+	}
+	return result
+}
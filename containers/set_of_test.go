@@ -0,0 +1,230 @@
+// Copyright (c) li. All rights reserved.
+// Use of this source code is governed by a MIT/X11
+// license that can be found in the LICENSE file.
+
+package containers
+
+import (
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestSetOfBasic(t *testing.T) {
+	set := NewSetOf(1, 2, 3, 3)
+	if set.Size() != 3 || !set.Contains(1) || !set.Contains(3) || set.Contains(4) {
+		t.Fatal()
+	}
+	if set.IsEmpty() || !NewSetOf[int]().IsEmpty() {
+		t.Fatal()
+	}
+
+	if set.Add(4) || !set.Add(4) || set.Size() != 4 {
+		t.Fatal()
+	}
+	if !set.Remove(4) || set.Remove(4) || set.Size() != 3 {
+		t.Fatal()
+	}
+
+	values := set.ToSlice()
+	sort.Ints(values)
+	if len(values) != 3 || values[0] != 1 || values[2] != 3 {
+		t.Fatal()
+	}
+
+	set.Clear()
+	if !set.IsEmpty() {
+		t.Fatal()
+	}
+}
+
+func TestSetOfInt64(t *testing.T) {
+	set := NewSetOf[int64](1, 2)
+	// An untyped constant is an int64 here, unlike Set where 1 != int64(1).
+	if !set.Contains(1) || !NewSet(int64(1)).Contains(int64(1)) || NewSet(int64(1)).Contains(1) {
+		t.Fatal()
+	}
+}
+
+func TestSetOfMutate(t *testing.T) {
+	set1 := NewSetOf(1, 2, 3)
+	set1.Union(NewSetOf(2, 3, 4))
+	if !set1.IsEqual(NewSetOf(1, 2, 3, 4)) {
+		t.Fatal()
+	}
+
+	set1.Intersect(NewSetOf(2, 3, 5))
+	if !set1.IsEqual(NewSetOf(2, 3)) {
+		t.Fatal()
+	}
+
+	set1.Subtract(NewSetOf(3))
+	if !set1.IsEqual(NewSetOf(2)) {
+		t.Fatal()
+	}
+
+	set1.Union(nil)
+	set1.Intersect(nil)
+	set1.Subtract(nil)
+	if !set1.IsEqual(NewSetOf(2)) {
+		t.Fatal()
+	}
+}
+
+func TestSetOfNonMutating(t *testing.T) {
+	set1 := NewSetOf(1, 2, 3)
+	set2 := NewSetOf(2, 3, 4)
+
+	if !set1.Unioned(set2).IsEqual(NewSetOf(1, 2, 3, 4)) ||
+		!set1.Intersected(set2).IsEqual(NewSetOf(2, 3)) ||
+		!set1.Subtracted(set2).IsEqual(NewSetOf(1)) ||
+		!set1.With(4, 5).IsEqual(NewSetOf(1, 2, 3, 4, 5)) ||
+		!set1.Without(1, 5).IsEqual(NewSetOf(2, 3)) {
+		t.Fatal()
+	}
+	if !set1.Unioned(nil).IsEqual(set1) ||
+		!set1.Intersected(nil).IsEqual(set1) ||
+		!set1.Subtracted(nil).IsEqual(set1) {
+		t.Fatal()
+	}
+
+	// The operands are not modified.
+	if !set1.IsEqual(NewSetOf(1, 2, 3)) || !set2.IsEqual(NewSetOf(2, 3, 4)) {
+		t.Fatal()
+	}
+}
+
+func TestSetOfIsSubset(t *testing.T) {
+	set1 := NewSetOf(1, 2, 3)
+	if set1.IsSubset(NewSetOf(2, 3, 4)) ||
+		!set1.IsSubset(set1) ||
+		!set1.IsSubset(NewSetOf(1, 2, 3, 4)) ||
+		set1.IsSubset(nil) {
+		t.Fatal()
+	}
+	if set1.IsEqual(NewSetOf(2, 3, 4)) || !set1.IsEqual(NewSetOf(3, 2, 1)) || set1.IsEqual(nil) {
+		t.Fatal()
+	}
+}
+
+func TestSetOfClone(t *testing.T) {
+	set1 := NewSetOf("a", "b")
+	set2 := set1.Clone()
+	set2.Add("c")
+	if !set1.IsEqual(NewSetOf("a", "b")) || set2.Size() != 3 {
+		t.Fatal()
+	}
+}
+
+func TestSetOfCallbacks(t *testing.T) {
+	set1 := NewSetOf(1, 2, 3, 4, 5)
+	sum := 0
+	set1.Foreach(func(v int) {
+		sum += v
+	})
+	if sum != 15 {
+		t.Fatal()
+	}
+
+	if !set1.Map(func(v int) int { return v * 100 }).IsEqual(NewSetOf(100, 200, 300, 400, 500)) {
+		t.Fatal()
+	}
+	if !set1.Filter(func(v int) bool { return v%2 == 0 }).IsEqual(NewSetOf(2, 4)) {
+		t.Fatal()
+	}
+	if !MapSetOf(set1, strconv.Itoa).IsEqual(NewSetOf("1", "2", "3", "4", "5")) {
+		t.Fatal()
+	}
+}
+
+const benchSize = 1000
+
+func BenchmarkSetAdd(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		set := NewSet()
+		for j := 0; j < benchSize; j++ {
+			set.Add(j)
+		}
+	}
+}
+
+func BenchmarkSetOfAdd(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		set := NewSetOf[int]()
+		for j := 0; j < benchSize; j++ {
+			set.Add(j)
+		}
+	}
+}
+
+func BenchmarkSetContains(b *testing.B) {
+	set := NewSet()
+	for j := 0; j < benchSize; j++ {
+		set.Add(j)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		set.Contains(i % (2 * benchSize))
+	}
+}
+
+func BenchmarkSetOfContains(b *testing.B) {
+	set := NewSetOf[int]()
+	for j := 0; j < benchSize; j++ {
+		set.Add(j)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		set.Contains(i % (2 * benchSize))
+	}
+}
+
+func BenchmarkSetUnion(b *testing.B) {
+	set1, set2 := NewSet(), NewSet()
+	for j := 0; j < benchSize; j++ {
+		set1.Add(j)
+		set2.Add(j + benchSize/2)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		set1.Clone().Union(set2)
+	}
+}
+
+func BenchmarkSetOfUnion(b *testing.B) {
+	set1, set2 := NewSetOf[int](), NewSetOf[int]()
+	for j := 0; j < benchSize; j++ {
+		set1.Add(j)
+		set2.Add(j + benchSize/2)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		set1.Unioned(set2)
+	}
+}
+
+func BenchmarkSetFilter(b *testing.B) {
+	set := NewSet()
+	for j := 0; j < benchSize; j++ {
+		set.Add(j)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		set.Filter(func(v interface{}) bool {
+			return v.(int)%2 == 0
+		})
+	}
+}
+
+func BenchmarkSetOfFilter(b *testing.B) {
+	set := NewSetOf[int]()
+	for j := 0; j < benchSize; j++ {
+		set.Add(j)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		set.Filter(func(v int) bool {
+			return v%2 == 0
+		})
+	}
+}